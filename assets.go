@@ -0,0 +1,452 @@
+package lemur
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ukiahsmith/lemur/scss"
+)
+
+const (
+	ASSETS_DIR_PATH = "assets"
+	SASS_DIR_PATH   = ASSETS_DIR_PATH + "/sass"
+	CSS_OUTPUT_DIR  = "css"
+)
+
+// assetPipeline keeps the manifest of the theme's assets: the files of
+// static/ and assets/, the stylesheets compiled from assets/sass/, and the
+// bundles made by the bundle func. Each asset is known by its logical name,
+// its path below static/ or assets/, e.g. "img/logo.png", or "css/style.css"
+// for assets/sass/style.scss.
+//
+// The manifest is built by load, and an asset whose source files changed is
+// rebuilt the next time it is used. The assets the template funcs return
+// URLs for are published, and only those are written by Build.
+type assetPipeline struct {
+	fsys fs.FS
+
+	mu            sync.Mutex
+	entries       map[string]*assetEntry // by logical name
+	fingerprinted map[string]string      // logical name by fingerprinted name
+	published     map[string]bool        // logical names of published assets, true when used at the logical name
+
+	imageProcessor
+}
+
+// assetEntry is an asset of the manifest.
+type assetEntry struct {
+	name          string   // logical name, e.g. "css/style.css"
+	fingerprinted string   // name with a content hash, e.g. "css/style.3f9a1c2b.css"
+	src           string   // source file in the theme, e.g. "assets/sass/style.scss"
+	fromStatic    bool     // src is below static/
+	content       []byte   // content of a compiled asset, nil for a file served as is
+	inputs        []string // the assets a bundle is built from, nil for any other asset
+	integrity     string   // Subresource Integrity value, "sha384-..."
+	etag          string
+	deps          []assetDep
+}
+
+// assetDep records a file an asset was built from, to tell when it changed.
+type assetDep struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func newAssetPipeline(fsys fs.FS) *assetPipeline {
+	return &assetPipeline{
+		fsys:          fsys,
+		entries:       make(map[string]*assetEntry),
+		fingerprinted: make(map[string]string),
+		published:     make(map[string]bool),
+	}
+}
+
+// funcs returns the template funcs of the asset pipeline.
+func (p *assetPipeline) funcs() template.FuncMap {
+	return template.FuncMap{
+		"scss":        p.scssURL,
+		"asset":       p.assetURL,
+		"integrity":   p.integrity,
+		"imageResize": p.imageResize,
+		"imageFill":   p.imageFill,
+		"bundle":      p.bundle,
+	}
+}
+
+// load builds the manifest from every file of static/ and assets/, compiling
+// the stylesheets of assets/sass/.
+func (p *assetPipeline) load() error {
+	for _, dir := range []string{STATIC_DIR_PATH, ASSETS_DIR_PATH} {
+		if _, err := fs.Stat(p.fsys, dir); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		err := fs.WalkDir(p.fsys, dir, func(src string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return fmt.Errorf("lemur asset: %w", err)
+			}
+			if d.IsDir() || hasHiddenSegment(src) {
+				return nil
+			}
+
+			name, ok := assetName(src)
+			if !ok {
+				return nil
+			}
+			_, _, err = p.entry(name)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// assetName returns the logical name of the theme file src, or false when src
+// is not an asset of its own, such as a SCSS partial.
+func assetName(src string) (string, bool) {
+	if rel := strings.TrimPrefix(src, SASS_DIR_PATH+"/"); rel != src {
+		if path.Ext(rel) != ".scss" || strings.HasPrefix(path.Base(rel), "_") {
+			return "", false
+		}
+		return path.Join(CSS_OUTPUT_DIR, strings.TrimSuffix(rel, ".scss")+".css"), true
+	}
+
+	for _, dir := range []string{STATIC_DIR_PATH, ASSETS_DIR_PATH} {
+		if rel := strings.TrimPrefix(src, dir+"/"); rel != src {
+			return rel, path.Ext(rel) != ".scss"
+		}
+	}
+
+	return "", false
+}
+
+// source returns the theme file the asset name is built from, looking in
+// static/, then in assets/sass/ for a stylesheet below css/, then in assets/.
+// SCSS sources are never assets themselves.
+func (p *assetPipeline) source(name string) (src string, compiled bool, ok bool) {
+	if p.fsys == nil || !fs.ValidPath(name) || hasHiddenSegment(name) {
+		return "", false, false
+	}
+
+	isFile := func(name string) bool {
+		info, err := fs.Stat(p.fsys, name)
+		return err == nil && info.Mode().IsRegular()
+	}
+
+	if src := path.Join(STATIC_DIR_PATH, name); isFile(src) {
+		return src, false, true
+	}
+
+	if rel := strings.TrimPrefix(name, CSS_OUTPUT_DIR+"/"); rel != name && path.Ext(rel) == ".css" && !strings.HasPrefix(path.Base(rel), "_") {
+		if src := path.Join(SASS_DIR_PATH, strings.TrimSuffix(rel, ".css")+".scss"); isFile(src) {
+			return src, true, true
+		}
+	}
+
+	if path.Ext(name) == ".scss" {
+		return "", false, false
+	}
+	if src := path.Join(ASSETS_DIR_PATH, name); isFile(src) {
+		return src, false, true
+	}
+
+	return "", false, false
+}
+
+// entry returns the asset with the logical name, building it when it is not in
+// the manifest or out of date. It returns false when there is no such asset.
+func (p *assetPipeline) entry(name string) (*assetEntry, bool, error) {
+	p.mu.Lock()
+	cached := p.entries[name]
+	p.mu.Unlock()
+
+	if cached != nil && p.fresh(cached) {
+		return cached, true, nil
+	}
+
+	var e *assetEntry
+	var err error
+	if cached != nil && cached.inputs != nil {
+		e, err = p.buildBundle(name, cached.inputs)
+	} else {
+		src, compiled, ok := p.source(name)
+		if !ok {
+			return nil, false, nil
+		}
+		e, err = p.build(name, src, compiled)
+	}
+	if err != nil {
+		return nil, true, err
+	}
+
+	p.add(e)
+
+	return e, true, nil
+}
+
+// add puts e in the manifest, replacing the asset with the same name.
+func (p *assetPipeline) add(e *assetEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if old := p.entries[e.name]; old != nil {
+		delete(p.fingerprinted, old.fingerprinted)
+	}
+	p.entries[e.name] = e
+	p.fingerprinted[e.fingerprinted] = e.name
+}
+
+// build reads or compiles the asset name from src and hashes it.
+func (p *assetPipeline) build(name string, src string, compiled bool) (*assetEntry, error) {
+	e := &assetEntry{name: name, src: src, fromStatic: strings.HasPrefix(src, STATIC_DIR_PATH+"/")}
+
+	var content []byte
+	deps := []string{src}
+	if compiled {
+		res, err := scss.Compile(p.fsys, src)
+		if err != nil {
+			return nil, fmt.Errorf("lemur scss: %w", err)
+		}
+		content, deps = res.CSS, res.Files
+		e.content = content
+	} else {
+		b, err := fs.ReadFile(p.fsys, src)
+		if err != nil {
+			return nil, fmt.Errorf("lemur asset: %w", err)
+		}
+		content = b
+	}
+
+	for _, dep := range deps {
+		info, err := fs.Stat(p.fsys, dep)
+		if err != nil {
+			return nil, fmt.Errorf("lemur asset: %w", err)
+		}
+		e.deps = append(e.deps, assetDep{path: dep, size: info.Size(), modTime: info.ModTime()})
+	}
+
+	e.hash(content)
+
+	return e, nil
+}
+
+// hash sets the integrity value, ETag and fingerprinted name of e from its
+// content.
+func (e *assetEntry) hash(content []byte) {
+	sum := sha512.Sum384(content)
+	e.integrity = "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+	e.etag = strconv.Quote(hex.EncodeToString(sum[:16]))
+
+	ext := path.Ext(e.name)
+	e.fingerprinted = strings.TrimSuffix(e.name, ext) + "." + hex.EncodeToString(sum[:4]) + ext
+}
+
+// fresh reports whether none of the files e was built from changed.
+func (p *assetPipeline) fresh(e *assetEntry) bool {
+	for _, dep := range e.deps {
+		info, err := fs.Stat(p.fsys, dep.path)
+		if err != nil || info.Size() != dep.size || !info.ModTime().Equal(dep.modTime) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lookupFingerprinted returns the asset served at the fingerprinted name, or
+// false when name is not the fingerprinted name of an asset's current content.
+func (p *assetPipeline) lookupFingerprinted(name string) (*assetEntry, bool, error) {
+	p.mu.Lock()
+	logical, ok := p.fingerprinted[name]
+	p.mu.Unlock()
+	if !ok {
+		return nil, false, nil
+	}
+
+	e, ok, err := p.entry(logical)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if e.fingerprinted != name {
+		return nil, false, nil
+	}
+
+	return e, true, nil
+}
+
+// open returns the content of the asset e and its modification time, which is
+// zero for a compiled asset.
+func (p *assetPipeline) open(e *assetEntry) ([]byte, time.Time, error) {
+	if e.content != nil {
+		return e.content, time.Time{}, nil
+	}
+
+	b, err := fs.ReadFile(p.fsys, e.src)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return b, e.deps[0].modTime, nil
+}
+
+// asset returns the asset name for the template func fn.
+func (p *assetPipeline) asset(fn string, name string) (*assetEntry, error) {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+
+	e, ok, err := p.entry(name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("lemur %s: %q is not in the asset manifest", fn, name)
+	}
+
+	return e, nil
+}
+
+// assetURL returns the fingerprinted URL path of the asset name, e.g.
+// "/css/style.3f9a1c2b.css" for "css/style.css". It is the "asset" template
+// func.
+func (p *assetPipeline) assetURL(name string) (string, error) {
+	e, err := p.asset("asset", name)
+	if err != nil {
+		return "", err
+	}
+	p.publish(e.name, false)
+
+	return "/" + e.fingerprinted, nil
+}
+
+// integrity returns the Subresource Integrity value of the asset name, for the
+// integrity attribute of a script or link element. It is the "integrity"
+// template func.
+func (p *assetPipeline) integrity(name string) (string, error) {
+	e, err := p.asset("integrity", name)
+	if err != nil {
+		return "", err
+	}
+	p.publish(e.name, false)
+
+	return e.integrity, nil
+}
+
+// scssURL compiles the stylesheet name, relative to assets/sass/, and returns
+// the URL path it is served at, e.g. "style.scss" is served at
+// "/css/style.css". It is the "scss" template func.
+func (p *assetPipeline) scssURL(name string) (string, error) {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	if path.Ext(name) != ".scss" || !fs.ValidPath(name) {
+		return "", fmt.Errorf("lemur scss: %q is not a .scss file below %s", name, SASS_DIR_PATH)
+	}
+
+	out := path.Join(CSS_OUTPUT_DIR, strings.TrimSuffix(name, ".scss")+".css")
+	e, ok, err := p.entry(out)
+	if err != nil {
+		return "", err
+	}
+	if !ok || e.content == nil {
+		return "", fmt.Errorf("lemur scss: %s not found, or is a partial", path.Join(SASS_DIR_PATH, name))
+	}
+	p.publish(out, true)
+
+	return "/" + out, nil
+}
+
+// publish marks the asset name as used by a template, at its logical name
+// too when logical is true, so Build writes it.
+func (p *assetPipeline) publish(name string, logical bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.published[name] = p.published[name] || logical
+}
+
+// unpublish forgets the published assets and images, before Build renders the
+// pages.
+func (p *assetPipeline) unpublish() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.published = make(map[string]bool)
+	p.mu.Unlock()
+
+	p.imagesMu.Lock()
+	p.publishedImages = make(map[string]bool)
+	p.imagesMu.Unlock()
+}
+
+// write writes every published asset below outDir at its fingerprinted name,
+// and at its logical name when it was used at that name, unless shadowed
+// reports a file of the site is written there.
+func (p *assetPipeline) write(outDir string, shadowed func(name string) bool) error {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	published := make(map[string]bool, len(p.published))
+	for name, logical := range p.published {
+		published[name] = logical
+	}
+	p.mu.Unlock()
+
+	for name, logical := range published {
+		e, ok, err := p.entry(name)
+		if err != nil {
+			return fmt.Errorf("lemur Build: %w", err)
+		}
+		if !ok {
+			continue
+		}
+
+		content, _, err := p.open(e)
+		if err != nil {
+			return fmt.Errorf("lemur Build: %w", err)
+		}
+
+		names := []string{e.fingerprinted}
+		if logical && !shadowed(e.name) {
+			names = append(names, e.name)
+		}
+		for _, name := range names {
+			if err := writeFile(filepath.Join(outDir, filepath.FromSlash(name)), content); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// serve writes the asset e as the response to r.
+func (p *assetPipeline) serve(w http.ResponseWriter, r *http.Request, e *assetEntry) {
+	content, modTime, err := p.open(e)
+	if err != nil {
+		serveFileError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(e.name)))
+	w.Header().Set("ETag", e.etag)
+	http.ServeContent(w, r, e.name, modTime, bytes.NewReader(content))
+}
@@ -0,0 +1,243 @@
+package lemur_test
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ukiahsmith/lemur"
+)
+
+func TestLemur_SCSS(t *testing.T) {
+	themeFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`<link rel="stylesheet" href="{{ scss "style.scss" }}">`)},
+		"layouts/broken/_index.html.tmpl":    &fstest.MapFile{Data: []byte(`{{ scss "missing.scss" }}`)},
+		"assets/sass/style.scss":             &fstest.MapFile{Data: []byte("@import 'vars';\nbody { color: $text; }")},
+		"assets/sass/_vars.scss":             &fstest.MapFile{Data: []byte("$text: #222;"), ModTime: time.Unix(1, 0)},
+	}
+
+	wh, err := lemur.New(themeFS, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	out, err := wh.Srender("_defaults", nil)
+	if err != nil {
+		t.Fatalf("Srender failed: %v", err)
+	}
+	if expected := `<link rel="stylesheet" href="/css/style.css">`; out != expected {
+		t.Errorf("Expected %q, but got %q", expected, out)
+	}
+
+	if _, err := wh.Srender("broken", nil); err == nil {
+		t.Errorf("Expected an error for a missing stylesheet, but got none")
+	}
+
+	handler := wh.StaticHandler()
+	get := func(p string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", p, nil))
+		return rec
+	}
+
+	rec := get("/css/style.css")
+	if rec.Code != 200 {
+		t.Fatalf("Expected status 200, but got %d", rec.Code)
+	}
+	if expected := "body {\n  color: #222;\n}\n"; rec.Body.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/css; charset=utf-8" {
+		t.Errorf("Expected Content-Type text/css, but got %q", ct)
+	}
+	etag := rec.Header().Get("ETag")
+
+	// A changed partial rebuilds the stylesheet.
+	themeFS["assets/sass/_vars.scss"] = &fstest.MapFile{Data: []byte("$text: #333333;"), ModTime: time.Unix(2, 0)}
+
+	rec = get("/css/style.css")
+	if expected := "body {\n  color: #333333;\n}\n"; rec.Body.String() != expected {
+		t.Errorf("Expected %q after the change, but got %q", expected, rec.Body.String())
+	}
+	if rec.Header().Get("ETag") == etag {
+		t.Errorf("Expected the ETag to change with the stylesheet")
+	}
+
+	if rec := get("/css/_vars.css"); rec.Code != 404 {
+		t.Errorf("Expected status 404 for a partial, but got %d", rec.Code)
+	}
+
+	outDir := t.TempDir()
+	if err := wh.Build(context.Background(), fstest.MapFS{"content/index.md": &fstest.MapFile{Data: []byte("# Home")}}, outDir); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(outDir, "css", "style.css"))
+	if err != nil {
+		t.Fatalf("Expected Build to write css/style.css: %v", err)
+	}
+	if !bytes.Contains(b, []byte("#333333")) {
+		t.Errorf("Expected the built stylesheet to be current, but got %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "css", "_vars.css")); !os.IsNotExist(err) {
+		t.Errorf("Expected partials not to be built, but got %v", err)
+	}
+
+	// A stylesheet of the site's static/ wins over the compiled one.
+	siteFS := fstest.MapFS{
+		"content/index.md":     &fstest.MapFile{Data: []byte("# Home")},
+		"static/css/style.css": &fstest.MapFile{Data: []byte("site")},
+	}
+	if err := wh.Build(context.Background(), siteFS, outDir); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(outDir, "css", "style.css")); string(b) != "site" {
+		t.Errorf("Expected the site's stylesheet, but got %q", b)
+	}
+}
+
+func TestLemur_SCSS_Error(t *testing.T) {
+	themeFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`x`)},
+		"assets/sass/style.scss":             &fstest.MapFile{Data: []byte("body {\n  color: $missing;\n}")},
+	}
+
+	_, err := lemur.New(themeFS, nil)
+	if err == nil {
+		t.Fatalf("Expected New to fail, but got no error")
+	}
+	if expected := "lemur scss: assets/sass/style.scss:2: undefined variable $missing"; err.Error() != expected {
+		t.Errorf("Expected %q, but got %q", expected, err.Error())
+	}
+
+	themeFS["assets/sass/style.scss"] = &fstest.MapFile{Data: []byte("body { color: red; }")}
+	var errs []error
+	wh, err := lemur.New(themeFS, nil, lemur.WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	// A stylesheet broken after New fails when it is served.
+	themeFS["assets/sass/style.scss"] = &fstest.MapFile{Data: []byte("body { color: $missing; }"), ModTime: time.Unix(1, 0)}
+
+	handler := wh.StaticHandler()
+	testCases := []struct {
+		Path   string
+		Status int
+	}{
+		{"/css/style.css", 500},
+		{"/img/missing.00000000.png", 404},
+	}
+
+	for _, tc := range testCases {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", tc.Path, nil))
+		if rec.Code != tc.Status {
+			t.Errorf("Expected status %d for %s, but got %d", tc.Status, tc.Path, rec.Code)
+		}
+		if strings.Contains(rec.Body.String(), "assets/sass") {
+			t.Errorf("Expected the response for %s not to reveal theme paths, but got %q", tc.Path, rec.Body.String())
+		}
+	}
+
+	// The error of the broken stylesheet goes to the error handler.
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error for the error handler, but got %v", errs)
+	}
+	if expected := "lemur StaticHandler: serving css/style.css: "; !strings.HasPrefix(errs[0].Error(), expected) {
+		t.Errorf("Expected an error starting with %q, but got %q", expected, errs[0].Error())
+	}
+}
+
+func TestLemur_AssetManifest(t *testing.T) {
+	themeFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ asset "css/style.css" }} {{ integrity "css/style.css" }} {{ asset "/js/app.js" }} {{ asset "img/logo.png" }}`)},
+		"layouts/missing/_index.html.tmpl":   &fstest.MapFile{Data: []byte(`{{ asset "css/nope.css" }}`)},
+		"assets/sass/style.scss":             &fstest.MapFile{Data: []byte("body { color: red; }")},
+		"assets/img/logo.png":                &fstest.MapFile{Data: []byte("png")},
+		"assets/img/product.png":             &fstest.MapFile{Data: []byte("unused")},
+		"static/js/app.js":                   &fstest.MapFile{Data: []byte("alert(1)")},
+	}
+
+	wh, err := lemur.New(themeFS, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	out, err := wh.Srender("_defaults", nil)
+	if err != nil {
+		t.Fatalf("Srender failed: %v", err)
+	}
+
+	// The fingerprint is the start of the sha384 of the content, and the "+" of
+	// the integrity value is escaped by html/template.
+	expected := "/css/style.dd10ed8e.css " +
+		"sha384-3RDtjniIi2E/mmvcXsOOfu/zxDaJoztI9CiXJ4wWylYEw5ReQ&#43;1HKelRqeQozAmx " +
+		"/js/app.1d3d84f4.js /img/logo.b5bd0d69.png"
+	if out != expected {
+		t.Errorf("Expected %q, but got %q", expected, out)
+	}
+
+	if _, err := wh.Srender("missing", nil); err == nil || !strings.Contains(err.Error(), "not in the asset manifest") {
+		t.Errorf("Expected an error for an unknown asset, but got %v", err)
+	}
+
+	handler := wh.StaticHandler()
+	testCases := []struct {
+		Name         string
+		Path         string
+		Status       int
+		Body         string
+		CacheControl string
+	}{
+		{"fingerprinted stylesheet", "/css/style.dd10ed8e.css", 200, "body {\n  color: red;\n}\n", "public, max-age=31536000, immutable"},
+		{"fingerprinted static", "/js/app.1d3d84f4.js", 200, "alert(1)", "public, max-age=31536000, immutable"},
+		{"fingerprinted asset", "/img/logo.b5bd0d69.png", 200, "png", "public, max-age=31536000, immutable"},
+		{"logical asset", "/img/logo.png", 200, "png", ""},
+		{"logical static", "/js/app.js", 200, "alert(1)", ""},
+		{"wrong fingerprint", "/css/style.00000000.css", 404, "", ""},
+		{"scss source", "/sass/style.scss", 404, "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", tc.Path, nil))
+
+			if rec.Code != tc.Status {
+				t.Fatalf("Expected status %d, but got %d", tc.Status, rec.Code)
+			}
+			if tc.Status != 200 {
+				return
+			}
+			if rec.Body.String() != tc.Body {
+				t.Errorf("Expected body %q, but got %q", tc.Body, rec.Body.String())
+			}
+			if cc := rec.Header().Get("Cache-Control"); cc != tc.CacheControl {
+				t.Errorf("Expected Cache-Control %q, but got %q", tc.CacheControl, cc)
+			}
+		})
+	}
+
+	// Only the assets the pages use are written, at the paths asset returned,
+	// and the files of static/ at their own.
+	outDir := t.TempDir()
+	if err := wh.Build(context.Background(), fstest.MapFS{"content/index.md": &fstest.MapFile{Data: []byte("# Home")}}, outDir); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	for _, name := range []string{"css/style.dd10ed8e.css", "js/app.1d3d84f4.js", "js/app.js", "img/logo.b5bd0d69.png"} {
+		if _, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("Expected Build to write %s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"css/style.css", "img/logo.png", "img/product.png"} {
+		if _, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Errorf("Expected Build not to write %s, but got %v", name, err)
+		}
+	}
+}
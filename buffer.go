@@ -0,0 +1,32 @@
+package lemur
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity above which a buffer is not returned to
+// the pool, so that one unusually large page does not pin its memory forever.
+const maxPooledBufferSize = 1 << 20
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// GetBuffer returns an empty buffer from the pool atomic renders use, see
+// WithAtomicRender. Return it with PutBuffer once its content is written.
+func GetBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// PutBuffer resets buf and returns it to the pool. Buffers that grew larger
+// than 1 MiB are dropped instead.
+func PutBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
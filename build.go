@@ -0,0 +1,351 @@
+package lemur
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	CONTENT_DIR_PATH = "content"
+	STATIC_DIR_PATH  = "static"
+)
+
+// buildFile is a file below the content directory to be built.
+type buildFile struct {
+	src  string // path in the source filesystem
+	name string // path relative to the content directory
+}
+
+// Build renders a static site into outDir.
+//
+// Every Markdown (.md, .markdown) and HTML (.html, .htm) file below content/
+// in srcFS is loaded with LoadPage and rendered with Render and a Data holding
+// the configured Site and the Page. Pages marked as drafts in their front
+// matter are skipped. Pages get pretty URLs: content/about.md is
+// written to about/index.html, and content/index.md and content/blog/index.md
+// to index.html and blog/index.html. Two content files written to the same
+// file, such as content/about.md and content/about/index.md, fail the build
+// before anything is written. Other files in content/ are copied next
+// to the pages, as are the files of the theme's static/ and layouts/_public/
+// directories, and then the files of static/ in srcFS, so a site can override a
+// theme file. Of the theme's assets only those the pages use are written, at
+// the paths the asset funcs returned.
+//
+// A page is rendered with the layout from its front matter, or else the
+// layout named after its directory below content/, e.g. "blog" for
+// content/blog/hello.md, falling back to the parent directory and then to
+// "_defaults", see Resolve for aliases.
+//
+// Pages are rendered in parallel. The first error stops the build, files
+// already written are left in outDir. The assets and images used while
+// rendering, see asset and imageResize, are written last.
+func (wh *Lemur) Build(ctx context.Context, srcFS fs.FS, outDir string) error {
+	files, err := contentFiles(srcFS)
+	if err != nil {
+		return fmt.Errorf("lemur Build: %w", err)
+	}
+	if err := checkOutputs(files); err != nil {
+		return err
+	}
+
+	// Written in reverse order of StaticHandler lookup, so the same file wins.
+	publicSrcs := []struct {
+		fsys fs.FS
+		dir  string
+	}{
+		{wh.fsys, path.Join(LAYOUTS_DIR_PATH, PUBLIC_DIR)},
+		{wh.fsys, STATIC_DIR_PATH},
+		{srcFS, STATIC_DIR_PATH},
+	}
+	for _, src := range publicSrcs {
+		if err := copyDir(src.fsys, src.dir, outDir); err != nil {
+			return fmt.Errorf("lemur Build: copying %s: %w", src.dir, err)
+		}
+	}
+
+	wh.assets.unpublish()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	jobs := make(chan buildFile)
+
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				if err := wh.buildFile(ctx, srcFS, outDir, f); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+queue:
+	for _, f := range files {
+		select {
+		case jobs <- f:
+		case <-ctx.Done():
+			break queue
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	shadowed := func(name string) bool {
+		for _, src := range publicSrcs {
+			if _, err := fs.Stat(src.fsys, path.Join(src.dir, name)); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+	if err := wh.assets.write(outDir, shadowed); err != nil {
+		return err
+	}
+
+	return wh.assets.writeImages(outDir)
+}
+
+// contentFiles lists the files below the content directory of srcFS, skipping
+// those whose names start with a '.'.
+func contentFiles(srcFS fs.FS) ([]buildFile, error) {
+	var files []buildFile
+
+	err := fs.WalkDir(srcFS, CONTENT_DIR_PATH, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Name()[0] == '.' && p != CONTENT_DIR_PATH {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		files = append(files, buildFile{src: p, name: strings.TrimPrefix(p, CONTENT_DIR_PATH+"/")})
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("source filesystem has no %s directory", CONTENT_DIR_PATH)
+		}
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// outputName returns the path below the output directory the content file is
+// written to.
+func (f buildFile) outputName() string {
+	if !isContentFile(f.name) {
+		return f.name
+	}
+
+	return path.Join(strings.TrimPrefix(pagePath(f.name), "/"), "index.html")
+}
+
+// checkOutputs returns an error naming both files when two content files would
+// be written to the same output file, e.g. about.md and about/index.md, drafts
+// included, as the page that wins would depend on the order they render in.
+func checkOutputs(files []buildFile) error {
+	sources := make(map[string]string, len(files))
+	for _, f := range files {
+		out := f.outputName()
+		if other, ok := sources[out]; ok {
+			return fmt.Errorf("lemur Build: %s and %s are both written to %s", other, f.src, out)
+		}
+		sources[out] = f.src
+	}
+
+	return nil
+}
+
+// buildFile renders a page, or copies any other content file, to its output
+// file below outDir.
+func (wh *Lemur) buildFile(ctx context.Context, srcFS fs.FS, outDir string, f buildFile) error {
+	src, err := fs.ReadFile(srcFS, f.src)
+	if err != nil {
+		return fmt.Errorf("lemur Build: %w", err)
+	}
+
+	if !isContentFile(f.name) {
+		return writeFile(filepath.Join(outDir, filepath.FromSlash(f.outputName())), src)
+	}
+
+	page, err := wh.LoadPage(f.name, src)
+	if err != nil {
+		return fmt.Errorf("lemur Build: %w", err)
+	}
+	if page.Draft {
+		return nil
+	}
+
+	layout := page.Layout
+	if layout == "" {
+		layout = wh.sectionLayout(page.Section)
+	}
+
+	var buf bytes.Buffer
+	if err := wh.RenderContext(ctx, &buf, layout, Data{Site: wh.Site(), Page: page}); err != nil {
+		return fmt.Errorf("lemur Build: rendering %s: %w", f.src, err)
+	}
+
+	return writeFile(filepath.Join(outDir, filepath.FromSlash(f.outputName())), buf.Bytes())
+}
+
+// pageContent converts the body of a content file to HTML. HTML content files
+// are trusted and used as is.
+func (wh *Lemur) pageContent(name string, body []byte) (template.HTML, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		return wh.markdownRenderer().Render(string(body))
+	}
+
+	return template.HTML(body), nil
+}
+
+// sectionLayout returns the layout for pages in section: the layout named
+// after the section if one resolves, else that of its parent section, else
+// "_defaults".
+func (wh *Lemur) sectionLayout(section string) string {
+	for section != "" {
+		if resolved, _, ok := wh.resolveLayout(section); ok {
+			return resolved
+		}
+
+		i := strings.LastIndex(section, "/")
+		if i < 0 {
+			break
+		}
+		section = section[:i]
+	}
+
+	return DEFAULT_TEMPLATE
+}
+
+func isContentFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown", ".html", ".htm":
+		return true
+	}
+
+	return false
+}
+
+// titleFromName derives a page title from a content file name, "hello-world"
+// becomes "Hello world". An index page is titled after its section.
+func titleFromName(name string, section string) string {
+	if name == "index" || name == "_index" {
+		if section == "" {
+			return ""
+		}
+		name = path.Base(section)
+	}
+
+	title := strings.NewReplacer("-", " ", "_", " ").Replace(name)
+	if title == "" {
+		return ""
+	}
+
+	r, size := utf8.DecodeRuneInString(title)
+	return string(unicode.ToUpper(r)) + title[size:]
+}
+
+// copyDir copies every file below dir in fsys to the same relative path below
+// outDir, skipping those whose names start with a '.'. A missing dir is not an
+// error.
+func copyDir(fsys fs.FS, dir string, outDir string) error {
+	if fsys == nil {
+		return nil
+	}
+	if _, err := fs.Stat(fsys, dir); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && d.Name()[0] == '.' {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		f, err := fsys.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		rel := strings.TrimPrefix(p, dir+"/")
+		return copyFile(filepath.Join(outDir, filepath.FromSlash(rel)), f)
+	})
+}
+
+func copyFile(name string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func writeFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return fmt.Errorf("lemur Build: %w", err)
+	}
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		return fmt.Errorf("lemur Build: %w", err)
+	}
+
+	return nil
+}
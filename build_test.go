@@ -0,0 +1,177 @@
+package lemur_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ukiahsmith/lemur"
+)
+
+func TestLemur_Build(t *testing.T) {
+	themeFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ .Site.Title }}|{{ .Page.Title }}|{{ .Page.Path }}|{{ block "main.html.tmpl" . }}{{ .Page.Content }}{{ end }}`)},
+		"layouts/blog/main.html.tmpl":        &fstest.MapFile{Data: []byte(`blog:{{ .Page.Section }}:{{ .Page.Content }}`)},
+		"static/css/style.css":               &fstest.MapFile{Data: []byte(`theme`)},
+		"static/robots.txt":                  &fstest.MapFile{Data: []byte(`theme robots`)},
+		"static/.DS_Store":                   &fstest.MapFile{Data: []byte(`finder`)},
+	}
+	srcFS := fstest.MapFS{
+		"content/index.md":            &fstest.MapFile{Data: []byte("# Home")},
+		"content/about-us.html":       &fstest.MapFile{Data: []byte("<p>About</p>")},
+		"content/blog/index.md":       &fstest.MapFile{Data: []byte("Posts")},
+		"content/blog/2021/hello.md":  &fstest.MapFile{Data: []byte("*Hi*")},
+		"content/blog/2021/photo.jpg": &fstest.MapFile{Data: []byte("jpeg")},
+		"content/.drafts/secret.md":   &fstest.MapFile{Data: []byte("secret")},
+		"content/draft.md":            &fstest.MapFile{Data: []byte("---\ndraft: true\n---\nDraft")},
+		"content/pens.md":             &fstest.MapFile{Data: []byte("+++\ntitle = \"All pens\"\nlayout = \"blog\"\n+++\nPens")},
+		"content/über-uns.md":         &fstest.MapFile{Data: []byte("Über")},
+		"static/robots.txt":           &fstest.MapFile{Data: []byte("site robots")},
+		"static/img/pangolin.webp":    &fstest.MapFile{Data: []byte("webp")},
+		"static/.git/config":          &fstest.MapFile{Data: []byte("git")},
+	}
+
+	baseURL, _ := url.Parse("https://example.com/")
+	wh, err := lemur.New(themeFS, nil, lemur.WithSite(lemur.Site{BaseURL: baseURL, Title: "Pens"}))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	outDir := t.TempDir()
+	if err := wh.Build(context.Background(), srcFS, outDir); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	testCases := []struct {
+		Name     string
+		Path     string
+		Expected string
+	}{
+		{"home", "index.html", "Pens||/|<h1 id=\"home\">Home</h1>\n"},
+		{"pretty URL", "about-us/index.html", "Pens|About us|/about-us/|<p>About</p>"},
+		{"multibyte title", "über-uns/index.html", "Pens|Über uns|/über-uns/|<p>Über</p>\n"},
+		{"section index", "blog/index.html", "Pens|Blog|/blog/|blog:blog:<p>Posts</p>\n"},
+		{"nested section falls back", "blog/2021/hello/index.html", "Pens|Hello|/blog/2021/hello/|blog:blog/2021:<p><em>Hi</em></p>\n"},
+		{"front matter layout", "pens/index.html", "Pens|All pens|/pens/|blog::<p>Pens</p>\n"},
+		{"content file copied", "blog/2021/photo.jpg", "jpeg"},
+		{"theme static", "css/style.css", "theme"},
+		{"site static overrides theme", "robots.txt", "site robots"},
+		{"site static", "img/pangolin.webp", "webp"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join(outDir, tc.Path))
+			if err != nil {
+				t.Fatalf("Failed to read output: %v", err)
+			}
+
+			if string(b) != tc.Expected {
+				t.Errorf("Expected %q, but got %q", tc.Expected, string(b))
+			}
+		})
+	}
+
+	for _, skipped := range []string{".drafts", "draft", ".DS_Store", ".git"} {
+		if _, err := os.Stat(filepath.Join(outDir, skipped)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected %s to be skipped, but got %v", skipped, err)
+		}
+	}
+}
+
+func TestLemur_Build_Errors(t *testing.T) {
+	themeFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ .Page.Missing }}`)},
+	}
+
+	wh, err := lemur.New(themeFS, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	testCases := []struct {
+		Name     string
+		Src      fstest.MapFS
+		Expected error
+	}{
+		{"no content directory", fstest.MapFS{"static/a.txt": &fstest.MapFile{Data: []byte("a")}}, nil},
+		{"render error", fstest.MapFS{"content/index.md": &fstest.MapFile{Data: []byte("x")}}, lemur.ErrExec},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := wh.Build(context.Background(), tc.Src, t.TempDir())
+			if err == nil {
+				t.Fatalf("Expected an error, but got nil")
+			}
+			if tc.Expected != nil && !errors.Is(err, tc.Expected) {
+				t.Errorf("Expected error to match %v, but got %v", tc.Expected, err)
+			}
+		})
+	}
+}
+
+func TestLemur_Build_DuplicateOutputs(t *testing.T) {
+	themeFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ .Page.Title }}`)},
+	}
+
+	wh, err := lemur.New(themeFS, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	testCases := []struct {
+		Name     string
+		Files    []string
+		Expected string
+	}{
+		{"page and section index", []string{"content/about.md", "content/about/index.md"}, "content/about/index.md and content/about.md are both written to about/index.html"},
+		{"index and _index", []string{"content/index.md", "content/_index.md"}, "content/_index.md and content/index.md are both written to index.html"},
+		{"markdown and html", []string{"content/pens.md", "content/pens.html"}, "content/pens.html and content/pens.md are both written to pens/index.html"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			srcFS := fstest.MapFS{}
+			for _, name := range tc.Files {
+				srcFS[name] = &fstest.MapFile{Data: []byte("x")}
+			}
+
+			outDir := t.TempDir()
+			err := wh.Build(context.Background(), srcFS, outDir)
+			if err == nil || !strings.Contains(err.Error(), tc.Expected) {
+				t.Errorf("Expected an error containing %q, but got %v", tc.Expected, err)
+			}
+			if entries, _ := os.ReadDir(outDir); len(entries) != 0 {
+				t.Errorf("Expected nothing to be written, but got %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestLemur_Build_Canceled(t *testing.T) {
+	themeFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ .Page.Title }}`)},
+	}
+	srcFS := fstest.MapFS{
+		"content/a.md": &fstest.MapFile{Data: []byte("a")},
+	}
+
+	wh, err := lemur.New(themeFS, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := wh.Build(ctx, srcFS, t.TempDir()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error to match %v, but got %v", context.Canceled, err)
+	}
+}
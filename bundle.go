@@ -0,0 +1,155 @@
+package lemur
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/ukiahsmith/lemur/minify"
+)
+
+// bundleSeparators join the minified assets of a bundle by its extension. A
+// script that does not end in a semicolon must not run into the next.
+var bundleSeparators = map[string]string{
+	".css": "\n",
+	".js":  ";\n",
+}
+
+// bundle concatenates the assets inputs, in order, into the minified asset
+// name and returns its fingerprinted URL path, e.g.
+//
+//	{{ bundle "css/main.css" (list "vendor/css/normalize.css" "css/style.css") }}
+//
+// returns "/css/main.3f9a1c2b.css". The inputs are asset names as for the
+// asset func, given one by one or as a slice, built with the list func in
+// templates. name must end in .css or .js and must not be a theme asset
+// itself. The bundle is added to the asset manifest, so asset and integrity
+// work for it once it is built, and it is rebuilt when one of its inputs
+// changes. It is the "bundle" template func.
+func (p *assetPipeline) bundle(name string, inputs ...interface{}) (string, error) {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	if _, ok := bundleSeparators[path.Ext(name)]; !ok || !fs.ValidPath(name) {
+		return "", fmt.Errorf("lemur bundle: %q is not a .css or .js file name", name)
+	}
+	if _, _, ok := p.source(name); ok {
+		return "", fmt.Errorf("lemur bundle: %q is an asset of the theme, choose another name for the bundle", name)
+	}
+
+	names, err := bundleInputs(inputs)
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	cached := p.entries[name]
+	p.mu.Unlock()
+
+	var e *assetEntry
+	if cached != nil && equalStrings(cached.inputs, names) {
+		e, _, err = p.entry(name)
+	} else {
+		e, err = p.buildBundle(name, names)
+		if err == nil {
+			p.add(e)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	p.publish(name, false)
+
+	return "/" + e.fingerprinted, nil
+}
+
+// bundleInputs returns the cleaned asset names of the bundle func's inputs.
+func bundleInputs(inputs []interface{}) ([]string, error) {
+	var names []string
+	add := func(v interface{}) error {
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("lemur bundle: expected asset names, got %T", v)
+		}
+		names = append(names, path.Clean(strings.TrimPrefix(s, "/")))
+		return nil
+	}
+
+	for _, input := range inputs {
+		switch v := input.(type) {
+		case []string:
+			for _, s := range v {
+				_ = add(s)
+			}
+		case []interface{}:
+			for _, s := range v {
+				if err := add(s); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			if err := add(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("lemur bundle: no assets to bundle")
+	}
+
+	return names, nil
+}
+
+// buildBundle minifies the assets inputs and concatenates them into the
+// bundle name.
+func (p *assetPipeline) buildBundle(name string, inputs []string) (*assetEntry, error) {
+	e := &assetEntry{name: name, inputs: inputs}
+	ext := path.Ext(name)
+
+	var buf bytes.Buffer
+	for i, input := range inputs {
+		in, err := p.asset("bundle", input)
+		if err != nil {
+			return nil, err
+		}
+		if in.inputs != nil {
+			return nil, fmt.Errorf("lemur bundle: %q is a bundle itself", input)
+		}
+
+		content, _, err := p.open(in)
+		if err != nil {
+			return nil, fmt.Errorf("lemur bundle: %w", err)
+		}
+		if ext == ".css" {
+			content = minify.CSS(content)
+		} else if content, err = minify.JS(content); err != nil {
+			return nil, fmt.Errorf("lemur bundle: %s: %w", in.name, err)
+		}
+
+		if i > 0 {
+			buf.WriteString(bundleSeparators[ext])
+		}
+		buf.Write(content)
+		e.deps = append(e.deps, in.deps...)
+	}
+
+	// A bundle's content is never nil, which would make it a file to read.
+	e.content = append([]byte{}, buf.Bytes()...)
+	e.hash(e.content)
+
+	return e, nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,148 @@
+package lemur_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ukiahsmith/lemur"
+)
+
+func TestLemur_Bundle(t *testing.T) {
+	themeFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ bundle "css/main.css" "vendor/reset.css" "/css/style.css" }}`)},
+		"layouts/scripts/_index.html.tmpl":   &fstest.MapFile{Data: []byte(`{{ bundle "js/app.js" . }}`)},
+		"layouts/listed/_index.html.tmpl":    &fstest.MapFile{Data: []byte(`{{ bundle "css/listed.css" (list "vendor/reset.css" "css/style.css") }}`)},
+		"assets/vendor/reset.css":            &fstest.MapFile{Data: []byte("html { margin: 0; }\n")},
+		"assets/sass/style.scss":             &fstest.MapFile{Data: []byte("body { color: red; }")},
+		"assets/js/a.js":                     &fstest.MapFile{Data: []byte("const a = 1 // one\n")},
+		"assets/js/b.js":                     &fstest.MapFile{Data: []byte("console.log( a )\n")},
+	}
+
+	wh, err := lemur.New(themeFS, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	handler := wh.StaticHandler()
+	get := func(p string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", p, nil))
+		return rec
+	}
+
+	fingerprinted := regexp.MustCompile(`^/css/main\.[0-9a-f]{8}\.css$`)
+
+	testCases := []struct {
+		Name     string
+		Layout   string
+		Data     interface{}
+		Pattern  *regexp.Regexp
+		Expected string
+	}{
+		{"stylesheets", "_defaults", nil, fingerprinted, "html{margin:0}\nbody{color:red}"},
+		{"stylesheets from list", "listed", nil, regexp.MustCompile(`^/css/listed\.[0-9a-f]{8}\.css$`), "html{margin:0}\nbody{color:red}"},
+		{"scripts from a slice", "scripts", []string{"js/a.js", "js/b.js"}, regexp.MustCompile(`^/js/app\.[0-9a-f]{8}\.js$`), "const a=1;\nconsole.log(a)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			url, err := wh.Srender(tc.Layout, tc.Data)
+			if err != nil {
+				t.Fatalf("Srender failed: %v", err)
+			}
+			if !tc.Pattern.MatchString(url) {
+				t.Fatalf("Expected a fingerprinted URL, but got %q", url)
+			}
+
+			rec := get(url)
+			if rec.Code != 200 {
+				t.Fatalf("Expected status 200, but got %d", rec.Code)
+			}
+			if rec.Body.String() != tc.Expected {
+				t.Errorf("Expected %q, but got %q", tc.Expected, rec.Body.String())
+			}
+			if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=31536000, immutable" {
+				t.Errorf("Expected an immutable Cache-Control, but got %q", cc)
+			}
+		})
+	}
+
+	first, _ := wh.Srender("_defaults", nil)
+
+	// A changed input rebuilds the bundle under a new fingerprint.
+	themeFS["assets/vendor/reset.css"] = &fstest.MapFile{Data: []byte("html { padding: 0; }\n"), ModTime: time.Unix(1, 0)}
+
+	url, err := wh.Srender("_defaults", nil)
+	if err != nil {
+		t.Fatalf("Srender failed: %v", err)
+	}
+	if url == first || !fingerprinted.MatchString(url) {
+		t.Errorf("Expected a new fingerprinted URL, but got %q", url)
+	}
+	if rec := get("/css/main.css"); rec.Body.String() != "html{padding:0}\nbody{color:red}" {
+		t.Errorf("Expected the rebuilt bundle, but got %q", rec.Body.String())
+	}
+	if rec := get(first); rec.Code != 404 {
+		t.Errorf("Expected status 404 for the old fingerprint, but got %d", rec.Code)
+	}
+
+	outDir := t.TempDir()
+	if err := wh.Build(context.Background(), fstest.MapFS{"content/index.md": &fstest.MapFile{Data: []byte("# Home")}}, outDir); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(strings.TrimPrefix(url, "/"))))
+	if err != nil {
+		t.Fatalf("Expected Build to write %s: %v", url, err)
+	}
+	if string(b) != "html{padding:0}\nbody{color:red}" {
+		t.Errorf("Expected the bundle in %s, but got %q", url, b)
+	}
+	for _, name := range []string{"css/main.css", "css/style.css", "vendor/reset.css"} {
+		if _, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Errorf("Expected Build not to write %s, but got %v", name, err)
+		}
+	}
+}
+
+func TestLemur_Bundle_Errors(t *testing.T) {
+	themeFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ bundle .Name .Inputs }}`)},
+		"assets/sass/style.scss":             &fstest.MapFile{Data: []byte("body { color: red; }")},
+		"assets/js/broken.js":                &fstest.MapFile{Data: []byte("const a = 1\nconst b = 'open\n")},
+	}
+
+	wh, err := lemur.New(themeFS, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	testCases := []struct {
+		Name     string
+		Bundle   string
+		Inputs   []string
+		Expected string
+	}{
+		{"unsupported extension", "img/all.png", []string{"css/style.css"}, "is not a .css or .js file name"},
+		{"theme asset", "css/style.css", []string{"css/style.css"}, "is an asset of the theme"},
+		{"no inputs", "css/main.css", nil, "no assets to bundle"},
+		{"missing input", "css/main.css", []string{"css/missing.css"}, "not in the asset manifest"},
+		{"broken script", "js/app.js", []string{"js/broken.js"}, "js/broken.js: line 2: unterminated string"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			data := map[string]interface{}{"Name": tc.Bundle, "Inputs": tc.Inputs}
+			_, err := wh.Srender("_defaults", data)
+			if err == nil || !strings.Contains(err.Error(), tc.Expected) {
+				t.Errorf("Expected an error containing %q, but got %v", tc.Expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+// Command lemur builds static sites from a lemur theme and a content
+// directory.
+//
+// Usage:
+//
+//	lemur build [flags]
+//	lemur serve [flags]
+//
+// The build command renders every page below <src>/content with the theme's
+// layouts into the output directory, and copies the static/ directories of
+// the theme and the source.
+//
+// The serve command serves the same site on localhost, rebuilding it when a
+// file changes and reloading the browser. Errors are shown in the browser.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ukiahsmith/lemur"
+	lemurhttp "github.com/ukiahsmith/lemur/http"
+)
+
+const usage = `usage: lemur <command> [flags]
+
+Commands:
+  build    render the content directory to a static site
+  serve    serve the site locally, rebuilding and reloading on changes
+
+Run "lemur <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+	switch os.Args[1] {
+	case "build":
+		err = runBuild(ctx, os.Args[2:])
+	case "serve":
+		err = runServe(ctx, os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "lemur: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lemur: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// siteFlags are the flags shared by the commands that render a site.
+type siteFlags struct {
+	theme   string
+	src     string
+	baseURL string
+	title   string
+}
+
+func (f *siteFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.theme, "theme", ".", "theme directory, containing layouts/")
+	fs.StringVar(&f.src, "src", ".", "site directory, containing content/ and static/")
+	fs.StringVar(&f.baseURL, "base-url", "", "absolute base URL of the site, e.g. https://example.com/")
+	fs.StringVar(&f.title, "title", "", "site title")
+}
+
+// options returns the lemur options selected by the flags.
+func (f *siteFlags) options() ([]lemur.Option, error) {
+	site := lemur.Site{Title: f.title}
+	if f.baseURL != "" {
+		u, err := url.Parse(f.baseURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -base-url: %w", err)
+		}
+		site.BaseURL = u
+	}
+
+	return []lemur.Option{lemur.WithSite(site)}, nil
+}
+
+func runBuild(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	var sf siteFlags
+	sf.register(fs)
+	out := fs.String("out", "public", "output directory")
+	minifyHTML := fs.Bool("minify", false, "minify the HTML of the pages")
+	_ = fs.Parse(args) // ExitOnError
+
+	opts, err := sf.options()
+	if err != nil {
+		return err
+	}
+	if *minifyHTML {
+		opts = append(opts, lemur.WithMinifyHTML())
+	}
+
+	wh, err := lemur.New(os.DirFS(sf.theme), nil, opts...)
+	if err != nil {
+		return err
+	}
+
+	return wh.Build(ctx, os.DirFS(sf.src), *out)
+}
+
+func runServe(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	var sf siteFlags
+	sf.register(fs)
+	addr := fs.String("addr", "localhost:1313", "address to listen on")
+	interval := fs.Duration("interval", 500*time.Millisecond, "how often to check for changed files")
+	_ = fs.Parse(args) // ExitOnError
+
+	opts, err := sf.options()
+	if err != nil {
+		return err
+	}
+
+	dev := lemurhttp.NewDevServer(os.DirFS(sf.theme), os.DirFS(sf.src), opts...)
+	defer dev.Close()
+
+	logErr := func(err error) {
+		log.Printf("build failed: %s", err)
+	}
+	if err := dev.Rebuild(ctx); err != nil {
+		logErr(err)
+	}
+	go dev.Watch(ctx, *interval, logErr)
+
+	srv := &http.Server{
+		Addr:    *addr,
+		Handler: dev,
+		// Live reload streams only end with their request context.
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("serving on http://%s", *addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package lemur
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// dateLayouts are the formats accepted for a date given as a string in front
+// matter.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseFrontMatter splits src into its front matter and body. The front matter
+// is YAML between "---" lines, TOML between "+++" lines, or a JSON object at
+// the very start of src. A src without front matter returns a nil map and src
+// unchanged.
+func ParseFrontMatter(src []byte) (map[string]interface{}, []byte, error) {
+	var meta map[string]interface{}
+
+	switch {
+	case hasDelimiter(src, "---"):
+		raw, body, err := splitFrontMatter(src, "---")
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := yaml.Unmarshal(raw, &meta); err != nil {
+			return nil, nil, fmt.Errorf("invalid YAML front matter: %w", err)
+		}
+		return meta, body, nil
+
+	case hasDelimiter(src, "+++"):
+		raw, body, err := splitFrontMatter(src, "+++")
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := toml.Unmarshal(raw, &meta); err != nil {
+			return nil, nil, fmt.Errorf("invalid TOML front matter: %w", err)
+		}
+		return meta, body, nil
+
+	case len(src) > 0 && src[0] == '{':
+		dec := json.NewDecoder(bytes.NewReader(src))
+		if err := dec.Decode(&meta); err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON front matter: %w", err)
+		}
+		body := bytes.TrimLeft(src[dec.InputOffset():], " \t")
+		return meta, trimNewline(body), nil
+	}
+
+	return nil, src, nil
+}
+
+// hasDelimiter reports whether src starts with a front matter delimiter line.
+func hasDelimiter(src []byte, delim string) bool {
+	if !bytes.HasPrefix(src, []byte(delim)) {
+		return false
+	}
+	rest := src[len(delim):]
+
+	return bytes.HasPrefix(rest, []byte("\n")) || bytes.HasPrefix(rest, []byte("\r\n"))
+}
+
+// splitFrontMatter returns the text between the opening delimiter line of src
+// and the closing one, and the body after it.
+func splitFrontMatter(src []byte, delim string) ([]byte, []byte, error) {
+	rest := trimNewline(src[len(delim):])
+
+	for offset := 0; offset <= len(rest); {
+		line := rest[offset:]
+		end := bytes.IndexByte(line, '\n')
+		if end >= 0 {
+			line = line[:end]
+		}
+
+		if string(bytes.TrimRight(line, "\r")) == delim {
+			body := rest[offset+len(line):]
+			return rest[:offset], trimNewline(body), nil
+		}
+
+		if end < 0 {
+			break
+		}
+		offset += end + 1
+	}
+
+	return nil, nil, fmt.Errorf("front matter is missing its closing %q", delim)
+}
+
+func trimNewline(b []byte) []byte {
+	if bytes.HasPrefix(b, []byte("\r\n")) {
+		return b[2:]
+	}
+
+	return bytes.TrimPrefix(b, []byte("\n"))
+}
+
+// LoadPage builds the Page for a content file from its source. name is the
+// path of the file relative to the content directory, e.g. "blog/hello.md",
+// and sets the Path and Section of the page as described for Build.
+//
+// The front matter keys title, layout, date, draft and tags set the matching
+// Page fields, every other key is stored in Page.Data. Without a title the
+// page is titled after its file name. The body of a Markdown file is rendered
+// with the Markdown renderer, see WithMarkdown, the body of an HTML file is
+// used as is.
+func (wh *Lemur) LoadPage(name string, src []byte) (Page, error) {
+	meta, body, err := ParseFrontMatter(src)
+	if err != nil {
+		return Page{}, fmt.Errorf("lemur LoadPage: %s: %w", name, err)
+	}
+
+	dir, file := path.Split(name)
+	base := strings.TrimSuffix(file, path.Ext(file))
+	section := strings.TrimSuffix(dir, "/")
+
+	page := Page{
+		Title:   titleFromName(base, section),
+		Path:    pagePath(name),
+		Section: section,
+	}
+
+	if err := page.setMeta(meta); err != nil {
+		return Page{}, fmt.Errorf("lemur LoadPage: %s: %w", name, err)
+	}
+
+	page.Content, err = wh.pageContent(name, body)
+	if err != nil {
+		return Page{}, fmt.Errorf("lemur LoadPage: %s: %w", name, err)
+	}
+
+	return page, nil
+}
+
+// pagePath returns the URL path of the page built from the content file name,
+// e.g. "/blog/hello/" for "blog/hello.md" and "/blog/" for "blog/index.md".
+func pagePath(name string) string {
+	dir, file := path.Split(name)
+	base := strings.TrimSuffix(file, path.Ext(file))
+
+	if base == "index" || base == "_index" {
+		return "/" + dir
+	}
+
+	return "/" + dir + base + "/"
+}
+
+// setMeta sets the known fields of p from front matter, and stores the other
+// keys in p.Data.
+func (p *Page) setMeta(meta map[string]interface{}) error {
+	for key, value := range meta {
+		var err error
+
+		switch strings.ToLower(key) {
+		case "title":
+			p.Title, err = metaString(key, value)
+		case "layout":
+			p.Layout, err = metaString(key, value)
+		case "date":
+			p.Date, err = metaDate(value)
+		case "draft":
+			draft, ok := value.(bool)
+			if !ok {
+				err = fmt.Errorf("front matter key %q must be a boolean, got %T", key, value)
+			}
+			p.Draft = draft
+		case "tags":
+			p.Tags, err = metaStrings(key, value)
+		default:
+			if p.Data == nil {
+				p.Data = make(map[string]interface{})
+			}
+			p.Data[key] = value
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func metaString(key string, value interface{}) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("front matter key %q must be a string, got %T", key, value)
+	}
+
+	return s, nil
+}
+
+func metaStrings(key string, value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := metaString(key, item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("front matter key %q must be a list of strings, got %T", key, value)
+}
+
+func metaDate(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("front matter date %q is not in a known format, e.g. 2006-01-02", v)
+	}
+
+	return time.Time{}, fmt.Errorf("front matter date must be a date, got %T", value)
+}
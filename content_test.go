@@ -0,0 +1,145 @@
+package lemur_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ukiahsmith/lemur"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Src      string
+		Expected map[string]interface{}
+		Body     string
+	}{
+		{"yaml", "---\ntitle: Hello\ncount: 3\n---\nBody\n", map[string]interface{}{"title": "Hello", "count": 3}, "Body\n"},
+		{"yaml crlf", "---\r\ntitle: Hello\r\n---\r\nBody", map[string]interface{}{"title": "Hello"}, "Body"},
+		{"toml", "+++\ntitle = \"Hello\"\ncount = 3\n+++\nBody", map[string]interface{}{"title": "Hello", "count": int64(3)}, "Body"},
+		{"json", "{\"title\": \"Hello\", \"count\": 3}\nBody", map[string]interface{}{"title": "Hello", "count": float64(3)}, "Body"},
+		{"none", "# Body\n---\n", nil, "# Body\n---\n"},
+		{"thematic break is not front matter", "---- \nBody", nil, "---- \nBody"},
+		{"empty yaml", "---\n---\nBody", nil, "Body"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			meta, body, err := lemur.ParseFrontMatter([]byte(tc.Src))
+			if err != nil {
+				t.Fatalf("ParseFrontMatter failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(meta, tc.Expected) {
+				t.Errorf("Expected front matter %#v, but got %#v", tc.Expected, meta)
+			}
+			if string(body) != tc.Body {
+				t.Errorf("Expected body %q, but got %q", tc.Body, body)
+			}
+		})
+	}
+}
+
+func TestParseFrontMatter_Errors(t *testing.T) {
+	testCases := []struct {
+		Name string
+		Src  string
+	}{
+		{"unclosed yaml", "---\ntitle: Hello\nBody"},
+		{"invalid yaml", "---\ntitle: [\n---\nBody"},
+		{"invalid toml", "+++\ntitle = \n+++\nBody"},
+		{"invalid json", "{\"title\": }\nBody"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if _, _, err := lemur.ParseFrontMatter([]byte(tc.Src)); err == nil {
+				t.Errorf("Expected an error, but got nil")
+			}
+		})
+	}
+}
+
+func TestLemur_LoadPage(t *testing.T) {
+	wh, err := lemur.New(fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`x`)},
+	}, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	src := "---\ntitle: Pelikan M800\nlayout: product\ndate: 2021-03-04\ndraft: true\ntags: [pens, pelikan]\nprice: 495\n---\n*Blue* stripes\n"
+	page, err := wh.LoadPage("shop/pelikan-m800.md", []byte(src))
+	if err != nil {
+		t.Fatalf("LoadPage failed: %v", err)
+	}
+
+	expected := lemur.Page{
+		Title:   "Pelikan M800",
+		Data:    map[string]interface{}{"price": 495},
+		Content: "<p><em>Blue</em> stripes</p>\n",
+		Path:    "/shop/pelikan-m800/",
+		Section: "shop",
+		Layout:  "product",
+		Date:    time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+		Draft:   true,
+		Tags:    []string{"pens", "pelikan"},
+	}
+	if !reflect.DeepEqual(page, expected) {
+		t.Errorf("Expected page %+v, but got %+v", expected, page)
+	}
+}
+
+func TestLemur_LoadPage_Errors(t *testing.T) {
+	wh, err := lemur.New(fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`x`)},
+	}, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	testCases := []struct {
+		Name     string
+		Src      string
+		Expected string
+	}{
+		{"title not a string", "---\ntitle: [a]\n---\n", `"title" must be a string`},
+		{"draft not a bool", "+++\ndraft = \"yes\"\n+++\n", `"draft" must be a boolean`},
+		{"bad date", "{\"date\": \"next week\"}", `date "next week" is not in a known format`},
+		{"bad tags", "{\"tags\": [1]}", `"tags" must be a string`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := wh.LoadPage("page.md", []byte(tc.Src))
+			if err == nil {
+				t.Fatalf("Expected an error, but got nil")
+			}
+			if !strings.Contains(err.Error(), tc.Expected) || !strings.Contains(err.Error(), "page.md") {
+				t.Errorf("Expected error to contain %q and the file name, but got %q", tc.Expected, err)
+			}
+		})
+	}
+}
+
+func TestLemur_RenderPage_Layout(t *testing.T) {
+	wh, err := lemur.New(fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ block "main.html.tmpl" . }}default{{ end }}`)},
+		"layouts/product/main.html.tmpl":     &fstest.MapFile{Data: []byte(`product {{ .Page.Title }}`)},
+	}, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := wh.RenderPage(&buf, "", lemur.Page{Title: "M800", Layout: "product"}); err != nil {
+		t.Fatalf("RenderPage failed: %v", err)
+	}
+
+	if expected := "product M800"; buf.String() != expected {
+		t.Errorf("Expected output %q, but got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,126 @@
+package lemur
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"reflect"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// WithContextFuncs registers template funcs that receive the context of the
+// render. Each func must take a context.Context as its first argument, which
+// templates do not pass, e.g. a func(ctx context.Context, id int) (Product,
+// error) is called from a template as {{ product 42 }}.
+//
+// Funcs receive the context passed to RenderContext, and
+// context.Background() when rendered with Render. Each render with context
+// funcs registered executes a fresh clone of the layout set, so only register
+// them when they are needed.
+func WithContextFuncs(ctxFuncs template.FuncMap) Option {
+	return func(wh *Lemur) {
+		if wh.ctxFuncs == nil {
+			wh.ctxFuncs = make(template.FuncMap, len(ctxFuncs))
+		}
+		for name, fn := range ctxFuncs {
+			wh.ctxFuncs[name] = fn
+		}
+	}
+}
+
+// registerContextFuncs checks the signatures of the context funcs and adds
+// them to the func map, bound to context.Background(), so that templates
+// calling them parse.
+func (wh *Lemur) registerContextFuncs() error {
+	bound, err := bindContextFuncs(context.Background(), wh.ctxFuncs)
+	if err != nil {
+		return err
+	}
+	for name, fn := range bound {
+		wh.funcs[name] = fn
+	}
+
+	return nil
+}
+
+// bindContext returns a clone of tmpl with the context funcs bound to ctx, or
+// tmpl itself when no context funcs are registered. Layout sets are never
+// executed directly when context funcs are registered, so they may always be
+// cloned.
+func (wh *Lemur) bindContext(ctx context.Context, tmpl *template.Template) (*template.Template, error) {
+	if len(wh.ctxFuncs) == 0 {
+		return tmpl, nil
+	}
+
+	bound, err := bindContextFuncs(ctx, wh.ctxFuncs)
+	if err != nil {
+		return nil, err
+	}
+
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone template set: %w", err)
+	}
+
+	return clone.Funcs(bound), nil
+}
+
+// bindContextFuncs returns a func map in which each func of ctxFuncs is
+// replaced by one without the leading context.Context argument, that calls it
+// with ctx.
+func bindContextFuncs(ctx context.Context, ctxFuncs template.FuncMap) (template.FuncMap, error) {
+	bound := make(template.FuncMap, len(ctxFuncs))
+	ctxValue := reflect.ValueOf(&ctx).Elem()
+
+	for name, fn := range ctxFuncs {
+		v := reflect.ValueOf(fn)
+		t := v.Type()
+		if t.Kind() != reflect.Func || t.NumIn() == 0 || t.In(0) != contextType {
+			return nil, fmt.Errorf("lemur: context func %q must take a context.Context as its first argument", name)
+		}
+
+		in := make([]reflect.Type, t.NumIn()-1)
+		for i := range in {
+			in[i] = t.In(i + 1)
+		}
+		out := make([]reflect.Type, t.NumOut())
+		for i := range out {
+			out[i] = t.Out(i)
+		}
+
+		variadic := t.IsVariadic()
+		bound[name] = reflect.MakeFunc(reflect.FuncOf(in, out, variadic), func(args []reflect.Value) []reflect.Value {
+			args = append([]reflect.Value{ctxValue}, args...)
+			if variadic {
+				return v.CallSlice(args)
+			}
+			return v.Call(args)
+		}).Interface()
+	}
+
+	return bound, nil
+}
+
+// contextWriter returns w wrapped so that writes fail with the context's error
+// once ctx is done. Template execution stops at the first failed write. A ctx
+// that can never be done returns w as is.
+func contextWriter(ctx context.Context, w io.Writer) io.Writer {
+	if ctx.Done() == nil {
+		return w
+	}
+	return &ctxWriter{ctx: ctx, w: w}
+}
+
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw *ctxWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
@@ -0,0 +1,150 @@
+package lemur_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ukiahsmith/lemur"
+)
+
+type ctxKey struct{}
+
+func TestLemur_RenderContext(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ greet "shopper" }} {{ join "a" "b" }}`)},
+		"layouts/cancel/_index.html.tmpl":    &fstest.MapFile{Data: []byte(`before{{ cancel }} after`)},
+		"layouts/wait/_index.html.tmpl":      &fstest.MapFile{Data: []byte(`{{ wait }}`)},
+	}
+
+	var cancelRender context.CancelFunc
+
+	ctxFuncs := map[string]interface{}{
+		"greet": func(ctx context.Context, name string) string {
+			if v, ok := ctx.Value(ctxKey{}).(string); ok {
+				return v + " " + name
+			}
+			return "hello " + name
+		},
+		"join": func(ctx context.Context, parts ...string) string {
+			return strings.Join(parts, "-")
+		},
+		"cancel": func(ctx context.Context) string {
+			cancelRender()
+			return ""
+		},
+		"wait": func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		},
+	}
+
+	wh, err := lemur.New(templateFS, nil, lemur.WithContextFuncs(ctxFuncs))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	t.Run("Context funcs without a context", func(t *testing.T) {
+		out, err := wh.Srender("", nil)
+		if err != nil {
+			t.Fatalf("Srender failed: %v", err)
+		}
+		if expected := "hello shopper a-b"; out != expected {
+			t.Errorf("Expected output %q, but got %q", expected, out)
+		}
+	})
+
+	t.Run("Context funcs receive the context", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), ctxKey{}, "welcome back")
+
+		var buf strings.Builder
+		if err := wh.RenderContext(ctx, &buf, "", nil); err != nil {
+			t.Fatalf("RenderContext failed: %v", err)
+		}
+		if expected := "welcome back shopper a-b"; buf.String() != expected {
+			t.Errorf("Expected output %q, but got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("Already cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var buf strings.Builder
+		err := wh.RenderContext(ctx, &buf, "", nil)
+		if !errors.Is(err, context.Canceled) || !errors.Is(err, lemur.ErrExec) {
+			t.Errorf("Expected a cancelled ErrExec, but got %v", err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("Expected no output, but got %q", buf.String())
+		}
+	})
+
+	t.Run("Cancelled while rendering", func(t *testing.T) {
+		var ctx context.Context
+		ctx, cancelRender = context.WithCancel(context.Background())
+		defer cancelRender()
+
+		var buf strings.Builder
+		err := wh.RenderContext(ctx, &buf, "cancel", nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected a cancelled error, but got %v", err)
+		}
+		if expected := "before"; buf.String() != expected {
+			t.Errorf("Expected output to stop at %q, but got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("Deadline reaches context funcs", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		var buf strings.Builder
+		err := wh.RenderContext(ctx, &buf, "wait", nil)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected a deadline exceeded error, but got %v", err)
+		}
+	})
+}
+
+func TestLemur_WithContextFuncs_ShouldErr(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`index`)},
+	}
+
+	_, err := lemur.New(templateFS, nil, lemur.WithContextFuncs(map[string]interface{}{
+		"noContext": func(name string) string { return name },
+	}))
+	if err == nil || !strings.Contains(err.Error(), `context func "noContext" must take a context.Context`) {
+		t.Errorf("Expected an error for a func without a context argument, but got %v", err)
+	}
+}
+
+func TestLemur_RenderContext_Atomic(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`before{{ cancel }} after`)},
+	}
+
+	var cancel context.CancelFunc
+	wh, err := lemur.New(templateFS, nil, lemur.WithAtomicRender(), lemur.WithContextFuncs(map[string]interface{}{
+		"cancel": func(ctx context.Context) string { cancel(); return "" },
+	}))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	var ctx context.Context
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var buf strings.Builder
+	if err := wh.RenderContext(ctx, &buf, "", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled error, but got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output in atomic mode, but got %q", buf.String())
+	}
+}
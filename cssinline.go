@@ -0,0 +1,324 @@
+package lemur
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// cssRule is a single simple selector of a stylesheet rule with the
+// declarations it applies.
+type cssRule struct {
+	tag         string // "" or "*" matches any element
+	id          string
+	classes     []string
+	specificity int
+	order       int
+	decls       []cssDecl
+}
+
+type cssDecl struct {
+	property  string
+	value     string
+	important bool
+}
+
+// simpleSelector matches the selectors that can be inlined: an optional tag
+// or universal selector followed by any number of classes and ids.
+var simpleSelector = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9-]*|\*)?((?:[.#][a-zA-Z_-][a-zA-Z0-9_-]*)*)$`)
+
+var cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// parseCSS returns the inlinable rules of css in source order. At-rules and
+// selectors that are not simple are skipped.
+func parseCSS(css string) []cssRule {
+	var rules []cssRule
+
+	css = cssComment.ReplaceAllString(css, "")
+	for {
+		css = strings.TrimSpace(css)
+		if css == "" {
+			return rules
+		}
+
+		if css[0] == '@' {
+			css = skipAtRule(css)
+			continue
+		}
+
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			return rules
+		}
+		end := strings.IndexByte(css[open:], '}')
+		if end < 0 {
+			return rules
+		}
+		end += open
+
+		decls := parseDeclarations(css[open+1 : end])
+		for _, sel := range strings.Split(css[:open], ",") {
+			rule, ok := parseSelector(strings.TrimSpace(sel))
+			if !ok {
+				continue
+			}
+			rule.order = len(rules)
+			rule.decls = decls
+			rules = append(rules, rule)
+		}
+
+		css = css[end+1:]
+	}
+}
+
+// skipAtRule returns css after the at-rule it starts with, either a statement
+// ending in ';' or a block with balanced braces.
+func skipAtRule(css string) string {
+	for i := 0; i < len(css); i++ {
+		switch css[i] {
+		case ';':
+			return css[i+1:]
+		case '{':
+			depth := 0
+			for j := i; j < len(css); j++ {
+				switch css[j] {
+				case '{':
+					depth++
+				case '}':
+					depth--
+					if depth == 0 {
+						return css[j+1:]
+					}
+				}
+			}
+			return ""
+		}
+	}
+
+	return ""
+}
+
+func parseSelector(sel string) (cssRule, bool) {
+	m := simpleSelector.FindStringSubmatch(sel)
+	if m == nil || sel == "" {
+		return cssRule{}, false
+	}
+
+	rule := cssRule{tag: strings.ToLower(m[1])}
+	if rule.tag != "" && rule.tag != "*" {
+		rule.specificity = 1
+	}
+
+	rest := m[2]
+	for rest != "" {
+		next := strings.IndexAny(rest[1:], ".#")
+		part := rest
+		if next >= 0 {
+			part = rest[:next+1]
+		}
+		rest = rest[len(part):]
+
+		if part[0] == '#' {
+			rule.id = part[1:]
+			rule.specificity += 100
+		} else {
+			rule.classes = append(rule.classes, part[1:])
+			rule.specificity += 10
+		}
+	}
+
+	return rule, true
+}
+
+// parseDeclarations parses the body of a rule or a style attribute.
+func parseDeclarations(body string) []cssDecl {
+	var decls []cssDecl
+
+	for _, decl := range strings.Split(body, ";") {
+		colon := strings.IndexByte(decl, ':')
+		if colon < 0 {
+			continue
+		}
+
+		property := strings.ToLower(strings.TrimSpace(decl[:colon]))
+		value := strings.TrimSpace(decl[colon+1:])
+		if property == "" || value == "" {
+			continue
+		}
+
+		important := false
+		if i := strings.Index(strings.ToLower(value), "!important"); i >= 0 {
+			important = true
+			value = strings.TrimSpace(value[:i])
+		}
+
+		decls = append(decls, cssDecl{property: property, value: value, important: important})
+	}
+
+	return decls
+}
+
+func (r cssRule) matches(n *html.Node) bool {
+	if r.tag != "" && r.tag != "*" && r.tag != n.Data {
+		return false
+	}
+
+	var id, class string
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "id":
+			id = attr.Val
+		case "class":
+			class = attr.Val
+		}
+	}
+
+	if r.id != "" && r.id != id {
+		return false
+	}
+
+	classes := strings.Fields(class)
+	for _, want := range r.classes {
+		found := false
+		for _, c := range classes {
+			if c == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// inlineCSS copies the declarations of the rules in css, and of any <style>
+// elements in doc, into the style attribute of every matching element in the
+// body of doc. Rules apply in order of specificity and then source order, an
+// existing style attribute overrides them, and !important declarations
+// override those that are not.
+func inlineCSS(doc string, css string) (string, error) {
+	root, err := html.Parse(strings.NewReader(doc))
+	if err != nil {
+		return "", fmt.Errorf("parsing email HTML: %w", err)
+	}
+
+	var sheets strings.Builder
+	sheets.WriteString(css)
+	walkHTML(root, func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "style" && n.FirstChild != nil {
+			sheets.WriteByte('\n')
+			sheets.WriteString(n.FirstChild.Data)
+		}
+	})
+
+	rules := parseCSS(sheets.String())
+	if len(rules) == 0 {
+		return doc, nil
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].specificity < rules[j].specificity
+	})
+
+	body := findElement(root, "body")
+	if body == nil {
+		body = root
+	}
+
+	walkHTML(body, func(n *html.Node) {
+		if n.Type != html.ElementNode || n.Data == "style" {
+			return
+		}
+		applyRules(n, rules)
+	})
+
+	var out strings.Builder
+	if err := html.Render(&out, root); err != nil {
+		return "", fmt.Errorf("rendering email HTML: %w", err)
+	}
+
+	return out.String(), nil
+}
+
+// applyRules sets the style attribute of n from the matching rules.
+func applyRules(n *html.Node, rules []cssRule) {
+	var names []string
+	values := make(map[string]cssDecl)
+
+	set := func(d cssDecl) {
+		old, ok := values[d.property]
+		if !ok {
+			names = append(names, d.property)
+		} else if old.important && !d.important {
+			return
+		}
+		values[d.property] = d
+	}
+
+	matched := false
+	for _, rule := range rules {
+		if !rule.matches(n) {
+			continue
+		}
+		matched = true
+		for _, d := range rule.decls {
+			set(d)
+		}
+	}
+	if !matched {
+		return
+	}
+
+	styleIdx := -1
+	for i, attr := range n.Attr {
+		if attr.Key == "style" {
+			styleIdx = i
+			for _, d := range parseDeclarations(attr.Val) {
+				set(d)
+			}
+		}
+	}
+
+	var style strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			style.WriteString(" ")
+		}
+		style.WriteString(name + ": " + values[name].value)
+		if values[name].important {
+			style.WriteString(" !important")
+		}
+		style.WriteString(";")
+	}
+
+	if styleIdx >= 0 {
+		n.Attr[styleIdx].Val = style.String()
+		return
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: "style", Val: style.String()})
+}
+
+func walkHTML(n *html.Node, fn func(*html.Node)) {
+	fn(n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkHTML(c, fn)
+	}
+}
+
+func findElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
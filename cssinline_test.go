@@ -0,0 +1,37 @@
+package lemur
+
+import "testing"
+
+func TestInlineCSS(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		CSS      string
+		Body     string
+		Expected string
+	}{
+		{"tag", `p { color: red }`, `<p>x</p>`, `<p style="color: red;">x</p>`},
+		{"class and id", `.a { color: red } #b { color: blue }`, `<p class="a" id="b">x</p>`, `<p class="a" id="b" style="color: blue;">x</p>`},
+		{"specificity beats order", `p.a { color: red } p { color: blue }`, `<p class="a">x</p>`, `<p class="a" style="color: red;">x</p>`},
+		{"compound classes", `.a.b { color: red }`, `<p class="a">x</p><p class="b a">y</p>`, `<p class="a">x</p><p class="b a" style="color: red;">y</p>`},
+		{"existing style wins", `p { color: red; margin: 0 }`, `<p style="color: blue">x</p>`, `<p style="color: blue; margin: 0;">x</p>`},
+		{"important wins", `p { color: red !important }`, `<p style="color: blue">x</p>`, `<p style="color: red !important;">x</p>`},
+		{"selector list", `h1, h2 { margin: 0 }`, `<h1>a</h1><h2>b</h2>`, `<h1 style="margin: 0;">a</h1><h2 style="margin: 0;">b</h2>`},
+		{"combinators skipped", `div p { color: red } a:hover { color: blue }`, `<div><p>x</p></div>`, `<div><p>x</p></div>`},
+		{"at rules skipped", `@import "x.css"; @media print { p { color: red } } /* c */ td { padding: 0 }`, `<p>x</p>`, `<p>x</p>`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			doc := "<html><head></head><body>" + tc.Body + "</body></html>"
+			out, err := inlineCSS(doc, tc.CSS)
+			if err != nil {
+				t.Fatalf("inlineCSS failed: %s", err)
+			}
+
+			expected := "<html><head></head><body>" + tc.Expected + "</body></html>"
+			if out != expected {
+				t.Errorf("Expected %q, but got %q", expected, out)
+			}
+		})
+	}
+}
@@ -1,12 +1,22 @@
 package lemur
 
-import "net/url"
+import (
+	"html/template"
+	"io"
+	"net/url"
+	"time"
 
+	"github.com/ukiahsmith/lemur/funcs"
+)
+
+// Data is the value templates receive when rendering a Page: the global Site
+// configured with WithSite, and the Page being rendered.
 type Data struct {
 	Site Site
 	Page Page
 }
 
+// Site holds the values shared by every page of a site.
 type Site struct {
 	BaseURL *url.URL
 
@@ -14,8 +24,137 @@ type Site struct {
 	Copyright string
 }
 
+// Page holds the values of a single rendered page.
 type Page struct {
 	Title string
 	Data  map[string]interface{}
 	Form  map[string]interface{}
+
+	// Content is the rendered body of a page built from a content file.
+	Content template.HTML
+
+	// Path is the URL path of a built page, e.g. "/blog/hello/", and Section
+	// the content directory it was built from, e.g. "blog", see Build.
+	Path    string
+	Section string
+
+	// Layout, Date, Draft and Tags are set from the front matter of a content
+	// file, see LoadPage. Layout selects the layout set that renders the page.
+	Layout string
+	Date   time.Time
+	Draft  bool
+	Tags   []string
+}
+
+// WithSite sets the Site merged into the data of every render as .Site, see
+// RenderPage, and returned by the "site" template func for data that has no
+// place for it, such as a struct. Unless the user funcs passed to New have
+// their own, it also replaces the "absURL" template func with one that joins a
+// single path argument onto site.BaseURL, so templates can write
+// `{{ absURL "cart" }}`, while the two argument form keeps working.
+func WithSite(site Site) Option {
+	return func(wh *Lemur) {
+		wh.site = &site
+	}
+}
+
+// registerSiteFuncs registers the "site" template func, returning the Site
+// configured with WithSite, or the zero Site, whatever data a template is
+// rendered with, and the Site's "absURL".
+func (wh *Lemur) registerSiteFuncs() {
+	site := wh.Site()
+	wh.funcs["site"] = func() Site { return site }
+
+	if wh.site != nil {
+		wh.funcs["absURL"] = funcs.SiteAbsURL(site.BaseURL)
+	}
+}
+
+// Site returns the Site configured with WithSite, or the zero Site.
+func (wh *Lemur) Site() Site {
+	if wh.site == nil {
+		return Site{}
+	}
+
+	return *wh.site
+}
+
+// RenderPage renders the named layout set with a Data holding the configured
+// Site and page, writing the output to w. layout is resolved as for Render,
+// an empty layout selects page.Layout, or "_defaults" if that is empty too.
+func (wh *Lemur) RenderPage(w io.Writer, layout string, page Page) error {
+	if layout == "" {
+		layout = page.Layout
+	}
+
+	return wh.Render(w, layout, Data{Site: wh.Site(), Page: page})
+}
+
+// withSite merges the configured Site into data, so that templates can read
+// .Site and .Page whatever they are rendered with:
+//
+//   - nil becomes a Data holding only the Site
+//   - a Data or *Data with a zero Site gets the configured Site
+//   - a Page or *Page is wrapped in a Data with the configured Site
+//   - a map[string]interface{} without a "Site" or "Page" key gets them, in a
+//     copy
+//
+// Any other data, and all data when no Site is configured, is passed through
+// unchanged, templates read the Site with the "site" func then. The caller's
+// data is never modified.
+func (wh *Lemur) withSite(data interface{}) interface{} {
+	if wh.site == nil {
+		return data
+	}
+	site := *wh.site
+
+	switch d := data.(type) {
+	case nil:
+		return Data{Site: site}
+
+	case Data:
+		if d.Site == (Site{}) {
+			d.Site = site
+		}
+		return d
+
+	case *Data:
+		if d == nil {
+			return Data{Site: site}
+		}
+		merged := *d
+		if merged.Site == (Site{}) {
+			merged.Site = site
+		}
+		return merged
+
+	case Page:
+		return Data{Site: site, Page: d}
+
+	case *Page:
+		if d == nil {
+			return Data{Site: site}
+		}
+		return Data{Site: site, Page: *d}
+
+	case map[string]interface{}:
+		_, hasSite := d["Site"]
+		_, hasPage := d["Page"]
+		if hasSite && hasPage {
+			return d
+		}
+		merged := make(map[string]interface{}, len(d)+2)
+		for k, v := range d {
+			merged[k] = v
+		}
+		if !hasSite {
+			merged["Site"] = site
+		}
+		if !hasPage {
+			merged["Page"] = Page{}
+		}
+		return merged
+	}
+
+	return data
 }
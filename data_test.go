@@ -0,0 +1,179 @@
+package lemur_test
+
+import (
+	"html/template"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ukiahsmith/lemur"
+)
+
+func TestLemur_WithSite(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com/shop")
+	site := lemur.Site{BaseURL: baseURL, Title: "YoYo-Pens", Copyright: "2021 YoYo-Pens"}
+
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ .Site.Title }} {{ absURL "cart" }}{{ with .Page.Title }} {{ . }}{{ end }}`)},
+	}
+
+	wh, err := lemur.New(templateFS, nil, lemur.WithSite(site))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	testCases := []struct {
+		Name     string
+		Data     interface{}
+		Expected string
+	}{
+		{"nil", nil, "YoYo-Pens https://example.com/shop/cart"},
+		{"Data without Site", lemur.Data{Page: lemur.Page{Title: "Home"}}, "YoYo-Pens https://example.com/shop/cart Home"},
+		{"Data with Site", lemur.Data{Site: lemur.Site{Title: "Other"}}, "Other https://example.com/shop/cart"},
+		{"pointer to Data", &lemur.Data{}, "YoYo-Pens https://example.com/shop/cart"},
+		{"Page", lemur.Page{Title: "Home"}, "YoYo-Pens https://example.com/shop/cart Home"},
+		{"pointer to Page", &lemur.Page{Title: "Home"}, "YoYo-Pens https://example.com/shop/cart Home"},
+		{"map without Site", map[string]interface{}{"Name": "x"}, "YoYo-Pens https://example.com/shop/cart"},
+		{"map with Site", map[string]interface{}{"Site": lemur.Site{Title: "Mine"}}, "Mine https://example.com/shop/cart"},
+		{"map with Page", map[string]interface{}{"Page": lemur.Page{Title: "Mine"}}, "YoYo-Pens https://example.com/shop/cart Mine"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			out, err := wh.Srender("", tc.Data)
+			if err != nil {
+				t.Fatalf("Srender failed: %v", err)
+			}
+
+			if out != tc.Expected {
+				t.Errorf("Expected output %q, but got %q", tc.Expected, out)
+			}
+		})
+	}
+}
+
+func TestLemur_WithSite_KeepsOtherData(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ .Name }}`)},
+		"layouts/site/_index.html.tmpl":      &fstest.MapFile{Data: []byte(`{{ .Name }} | {{ site.Title }}`)},
+	}
+
+	testCases := []struct {
+		Name     string
+		Layout   string
+		Data     interface{}
+		Options  []lemur.Option
+		Expected string
+	}{
+		{"struct", "", struct{ Name string }{"M800"}, []lemur.Option{lemur.WithSite(lemur.Site{Title: "YoYo-Pens"})}, "M800"},
+		{"map[string]string", "", map[string]string{"Name": "M800"}, []lemur.Option{lemur.WithSite(lemur.Site{Title: "YoYo-Pens"})}, "M800"},
+		{"site func", "site", struct{ Name string }{"M800"}, []lemur.Option{lemur.WithSite(lemur.Site{Title: "YoYo-Pens"})}, "M800 | YoYo-Pens"},
+		{"site func without a Site", "site", struct{ Name string }{"M800"}, nil, "M800 | "},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			wh, err := lemur.New(templateFS, nil, tc.Options...)
+			if err != nil {
+				t.Fatalf("lemur.New failed during setup: %v", err)
+			}
+
+			out, err := wh.Srender(tc.Layout, tc.Data)
+			if err != nil {
+				t.Fatalf("Srender failed: %v", err)
+			}
+			if out != tc.Expected {
+				t.Errorf("Expected output %q, but got %q", tc.Expected, out)
+			}
+		})
+	}
+}
+
+func TestLemur_WithSite_UserAbsURL(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com")
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ absURL "cart" }}`)},
+	}
+	userFuncs := template.FuncMap{
+		"absURL": func(p string) string { return "https://cdn.example.com/" + p },
+	}
+
+	wh, err := lemur.New(templateFS, userFuncs, lemur.WithSite(lemur.Site{BaseURL: baseURL}))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	out, err := wh.Srender("", nil)
+	if err != nil {
+		t.Fatalf("Srender failed: %v", err)
+	}
+	if expected := "https://cdn.example.com/cart"; out != expected {
+		t.Errorf("Expected the user's absURL, %q, but got %q", expected, out)
+	}
+}
+
+func TestLemur_WithSite_KeepsCallerData(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ .Site.Title }}`)},
+	}
+
+	wh, err := lemur.New(templateFS, nil, lemur.WithSite(lemur.Site{Title: "YoYo-Pens"}))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	data := map[string]interface{}{"Name": "x"}
+	if _, err := wh.Srender("", data); err != nil {
+		t.Fatalf("Srender failed: %v", err)
+	}
+
+	if _, ok := data["Site"]; ok {
+		t.Errorf("Expected the caller's map to be left unmodified, but got %v", data)
+	}
+}
+
+func TestLemur_RenderPage(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com")
+
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ .Page.Title }} | {{ .Site.Title }} | {{ .Site.Copyright }}`)},
+		"layouts/product/main.html.tmpl":     &fstest.MapFile{Data: []byte(`unused`)},
+	}
+
+	wh, err := lemur.New(templateFS, nil, lemur.WithSite(lemur.Site{BaseURL: baseURL, Title: "YoYo-Pens", Copyright: "2021"}))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := wh.RenderPage(&buf, "product", lemur.Page{Title: "Pelikan M800"}); err != nil {
+		t.Fatalf("RenderPage failed: %v", err)
+	}
+
+	expected := "Pelikan M800 | YoYo-Pens | 2021"
+	if buf.String() != expected {
+		t.Errorf("Expected output %q, but got %q", expected, buf.String())
+	}
+}
+
+func TestLemur_RenderPage_NoSite(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ .Page.Title }}|{{ .Site.Title }}`)},
+	}
+
+	wh, err := lemur.New(templateFS, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := wh.RenderPage(&buf, "", lemur.Page{Title: "Home"}); err != nil {
+		t.Fatalf("RenderPage failed: %v", err)
+	}
+
+	expected := "Home|"
+	if buf.String() != expected {
+		t.Errorf("Expected output %q, but got %q", expected, buf.String())
+	}
+}
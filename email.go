@@ -0,0 +1,171 @@
+package lemur
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+)
+
+// EMAIL_SUBJECT_TEMPLATE is the name of the template, defined in the HTML
+// templates of a layout set, that RenderEmail renders as the subject.
+const EMAIL_SUBJECT_TEMPLATE = "subject"
+
+// Email is a rendered email, see RenderEmail.
+type Email struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// WithEmailCSS enables CSS inlining for RenderEmail. The rules of the given
+// stylesheets, read from the theme filesystem on every render, and of any
+// <style> elements in the rendered HTML are copied into the style attribute of
+// each matching element, since many email clients ignore stylesheets.
+//
+// Only simple selectors are inlined: a tag, a universal selector, classes and
+// an id, e.g. "p", "td.total" or "#footer". Rules using combinators or
+// pseudo-classes, and at-rules such as @media, are left to the <style>
+// element.
+func WithEmailCSS(paths ...string) Option {
+	return func(wh *Lemur) {
+		wh.emailCSS = append(wh.emailCSS, paths...)
+		wh.inlineCSS = true
+	}
+}
+
+// RenderEmail renders an email from a single layout set: the HTML part from
+// "_index.html.tmpl", the optional text part from "_index.txt.tmpl", and the
+// subject from a template named "subject", e.g. defined in the layout with
+// {{ define "subject" }}Your order {{ .Page.Title }}{{ end }}. A missing text
+// part or subject is left empty. The subject is unescaped and its whitespace
+// collapsed to single spaces.
+//
+// CSS is inlined into the HTML part when enabled with WithEmailCSS.
+func (wh *Lemur) RenderEmail(layout string, data interface{}) (*Email, error) {
+	var email Email
+
+	htmlPart, err := wh.SrenderTemplate(layout, DEFAULT_TEMPLATE_INDEX, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if wh.inlineCSS {
+		htmlPart, err = wh.inlineEmailCSS(htmlPart)
+		if err != nil {
+			return nil, fmt.Errorf("lemur RenderEmail: %w", err)
+		}
+	}
+	email.HTML = htmlPart
+
+	email.Text, err = wh.SrenderTemplate(layout, TEXT_TEMPLATE_INDEX, data)
+	if err != nil && !errors.Is(err, ErrUnknownTemplate) {
+		return nil, err
+	}
+
+	subject, err := wh.SrenderTemplate(layout, EMAIL_SUBJECT_TEMPLATE, data)
+	if err != nil && !errors.Is(err, ErrUnknownTemplate) {
+		return nil, err
+	}
+	email.Subject = strings.Join(strings.Fields(html.UnescapeString(subject)), " ")
+
+	return &email, nil
+}
+
+// inlineEmailCSS reads the configured stylesheets and inlines them, and any
+// <style> elements, into doc.
+func (wh *Lemur) inlineEmailCSS(doc string) (string, error) {
+	var css strings.Builder
+	for _, p := range wh.emailCSS {
+		b, err := fs.ReadFile(wh.fsys, p)
+		if err != nil {
+			return "", fmt.Errorf("reading email stylesheet %s: %w", p, err)
+		}
+		css.Write(b)
+		css.WriteByte('\n')
+	}
+
+	return inlineCSS(doc, css.String())
+}
+
+// WriteTo writes the Subject, MIME-Version and Content-Type headers and the
+// body of the email to w, as a multipart/alternative message when it has a
+// text part and as a single text/html part otherwise. Address headers such as
+// From and To are written by the caller before calling WriteTo.
+func (e *Email) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+
+	header := textproto.MIMEHeader{}
+	header.Set("MIME-Version", "1.0")
+	if e.Subject != "" {
+		header.Set("Subject", mime.QEncoding.Encode("utf-8", e.Subject))
+	}
+
+	if e.Text == "" {
+		header.Set("Content-Type", "text/html; charset=utf-8")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		writeHeader(&buf, header)
+		if err := writeQuotedPrintable(&buf, e.HTML); err != nil {
+			return 0, err
+		}
+		return buf.WriteTo(w)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", e.Text},
+		{"text/html; charset=utf-8", e.HTML},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return 0, err
+		}
+		if err := writeQuotedPrintable(pw, part.content); err != nil {
+			return 0, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return 0, err
+	}
+
+	header.Set("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	writeHeader(&buf, header)
+	body.WriteTo(&buf) // a bytes.Buffer never fails to write
+
+	return buf.WriteTo(w)
+}
+
+// writeHeader writes header in a fixed order followed by the blank line that
+// ends it.
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	for _, key := range []string{"Subject", "MIME-Version", "Content-Type", "Content-Transfer-Encoding"} {
+		if v := header.Get(key); v != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", key, v)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+func writeQuotedPrintable(w io.Writer, s string) error {
+	qw := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qw, s); err != nil {
+		return err
+	}
+
+	return qw.Close()
+}
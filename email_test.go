@@ -0,0 +1,164 @@
+package lemur_test
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ukiahsmith/lemur"
+)
+
+func emailTestFS() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`<html><head><style>p { margin: 0 }</style></head><body>{{ block "main.html.tmpl" . }}{{ end }}</body></html>`)},
+		"layouts/order/main.html.tmpl":       &fstest.MapFile{Data: []byte(`{{ define "subject" }}Order  {{ . }} &amp; receipt{{ end }}<p class="total">Total for {{ . }}</p>`)},
+		"layouts/order/_index.txt.tmpl":      &fstest.MapFile{Data: []byte(`Total for {{ . }}`)},
+		"layouts/notice/main.html.tmpl":      &fstest.MapFile{Data: []byte(`<p>Notice</p>`)},
+		"assets/css/email.css":               &fstest.MapFile{Data: []byte(`.total { font-weight: bold } @media (max-width: 600px) { p { margin: 4px } }`)},
+	}
+}
+
+func TestLemur_RenderEmail(t *testing.T) {
+	wh, err := lemur.New(emailTestFS(), nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	testCases := []struct {
+		Name     string
+		Layout   string
+		Expected lemur.Email
+	}{
+		{
+			Name:   "html, text and subject",
+			Layout: "order",
+			Expected: lemur.Email{
+				Subject: "Order #42 & receipt",
+				HTML:    `<html><head><style>p { margin: 0 }</style></head><body><p class="total">Total for #42</p></body></html>`,
+				Text:    "Total for #42",
+			},
+		},
+		{
+			Name:   "html only",
+			Layout: "notice",
+			Expected: lemur.Email{
+				HTML: `<html><head><style>p { margin: 0 }</style></head><body><p>Notice</p></body></html>`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			email, err := wh.RenderEmail(tc.Layout, "#42")
+			if err != nil {
+				t.Fatalf("RenderEmail failed: %v", err)
+			}
+
+			if *email != tc.Expected {
+				t.Errorf("Expected email %+v, but got %+v", tc.Expected, *email)
+			}
+		})
+	}
+}
+
+func TestLemur_RenderEmail_InlineCSS(t *testing.T) {
+	wh, err := lemur.New(emailTestFS(), nil, lemur.WithEmailCSS("assets/css/email.css"))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	email, err := wh.RenderEmail("order", "#42")
+	if err != nil {
+		t.Fatalf("RenderEmail failed: %v", err)
+	}
+
+	expected := `<p class="total" style="margin: 0; font-weight: bold;">Total for #42</p>`
+	if !strings.Contains(email.HTML, expected) {
+		t.Errorf("Expected HTML to contain %q, but got %q", expected, email.HTML)
+	}
+}
+
+func TestLemur_RenderEmail_MissingStylesheet(t *testing.T) {
+	wh, err := lemur.New(emailTestFS(), nil, lemur.WithEmailCSS("assets/css/missing.css"))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	if _, err := wh.RenderEmail("order", "#42"); err == nil {
+		t.Errorf("Expected an error for a missing stylesheet, but got nil")
+	}
+}
+
+func TestEmail_WriteTo(t *testing.T) {
+	email := lemur.Email{
+		Subject: "Your order ✓",
+		HTML:    "<p>Total</p>",
+		Text:    "Total",
+	}
+
+	var buf strings.Builder
+	buf.WriteString("From: shop@example.com\r\nTo: buyer@example.com\r\n")
+	if _, err := email.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != email.Subject {
+		t.Errorf("Expected subject %q, but got %q (%v)", email.Subject, subject, err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected multipart/alternative, but got %q (%v)", mediaType, err)
+	}
+
+	var parts []string
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read part: %v", err)
+		}
+		// NextPart decodes quoted-printable parts.
+		b, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("Failed to read part body: %v", err)
+		}
+		parts = append(parts, part.Header.Get("Content-Type")+": "+string(b))
+	}
+
+	expected := []string{"text/plain; charset=utf-8: Total", "text/html; charset=utf-8: <p>Total</p>"}
+	if strings.Join(parts, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected parts %q, but got %q", expected, parts)
+	}
+}
+
+func TestEmail_WriteTo_HTMLOnly(t *testing.T) {
+	email := lemur.Email{HTML: "<p>Total</p>"}
+
+	var buf strings.Builder
+	if _, err := email.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+
+	if ct := msg.Header.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Expected Content-Type %q, but got %q", "text/html; charset=utf-8", ct)
+	}
+}
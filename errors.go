@@ -1,5 +1,15 @@
 package lemur
 
+import (
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"regexp"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+)
+
 // Error is the base error type for api errors.
 type Error string
 
@@ -10,4 +20,201 @@ func (e Error) Error() string {
 // Errors returned by the api.
 const (
 	ErrTemplateDir = Error("lemur: error with supplied template directory")
+
+	ErrUnknownLayout   = Error("lemur: unknown layout")
+	ErrUnknownTemplate = Error("lemur: unknown template")
+	ErrExec            = Error("lemur: error executing template")
 )
+
+// LoadError describes a single template file that failed to load.
+type LoadError struct {
+	// Layout is the name of the layout set the file belongs to, e.g.
+	// "_defaults" or "mytemplate".
+	Layout string
+	// Path is the path of the file, or of the layout directory, within the
+	// template filesystem.
+	Path string
+	// Line and Col locate the error in the file when it was reported by the
+	// template parser. Either is zero when unknown.
+	Line int
+	Col  int
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *LoadError) Error() string {
+	switch {
+	case e.Line > 0 && e.Col > 0:
+		return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Col, e.Err)
+	case e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Err)
+	case e.Path != "":
+		return fmt.Sprintf("%s: %s", e.Path, e.Err)
+	}
+	return e.Err.Error()
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// LoadErrors lists every template file that failed to load. It matches
+// ErrTemplateDir with errors.Is, and errors.As may be used to retrieve it from
+// the error returned by New or Validate.
+type LoadErrors []*LoadError
+
+func (e LoadErrors) Error() string {
+	if len(e) == 1 {
+		return fmt.Sprintf("%s: %s", ErrTemplateDir, e[0])
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d templates failed to load:", ErrTemplateDir, len(e))
+	for _, le := range e {
+		b.WriteString("\n\t")
+		b.WriteString(le.Error())
+	}
+	return b.String()
+}
+
+// Is reports whether target is ErrTemplateDir, or matches the error of any of
+// the listed failures.
+func (e LoadErrors) Is(target error) bool {
+	if target == ErrTemplateDir {
+		return true
+	}
+	for _, le := range e {
+		if errors.Is(le, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// add appends err to e. A LoadErrors is flattened into e, any other error is
+// recorded against layout and path. A nil err is ignored, as is a failure at a
+// path and position already listed; files in _defaults are parsed both into
+// the base template and into the _defaults layout set, and would otherwise be
+// reported twice.
+func (e *LoadErrors) add(layout string, path string, err error) {
+	if err == nil {
+		return
+	}
+
+	var errs LoadErrors
+	if errors.As(err, &errs) {
+		for _, le := range errs {
+			e.append(le)
+		}
+		return
+	}
+
+	line, col := parseErrorPosition(err)
+	e.append(&LoadError{Layout: layout, Path: path, Line: line, Col: col, Err: err})
+}
+
+func (e *LoadErrors) append(le *LoadError) {
+	for _, existing := range *e {
+		if existing.Path == le.Path && existing.Line == le.Line && existing.Col == le.Col {
+			return
+		}
+	}
+	*e = append(*e, le)
+}
+
+// err returns e as an error, or nil when it is empty.
+func (e LoadErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// RenderError describes a failed render. Its Kind is one of ErrUnknownLayout,
+// ErrUnknownTemplate or ErrExec, and errors.Is matches the error against it.
+type RenderError struct {
+	Kind error
+	// Layout and Entry are the layout set and entry template that were
+	// requested. Resolved is the layout set Layout resolved to through
+	// aliases and fallbacks, it is empty for ErrUnknownLayout.
+	Layout   string
+	Resolved string
+	Entry    string
+	// Template is the name of the template that failed to execute, which may
+	// be a partial called from Entry. Line and Col locate the failure within
+	// it. They are only set for ErrExec, and Line and Col are zero when
+	// unknown.
+	Template string
+	Line     int
+	Col      int
+	// Err is the underlying error from the template package, if any.
+	Err error
+}
+
+func (e *RenderError) Error() string {
+	switch e.Kind {
+	case ErrUnknownLayout:
+		return fmt.Sprintf("lemur Render: no template with name %q", e.Layout)
+	case ErrUnknownTemplate:
+		return fmt.Sprintf("lemur Render: no entry template %q in layout %q", e.Entry, e.Layout)
+	}
+	return fmt.Sprintf("lemur Render: could not render template: %s", e.Err)
+}
+
+// Is reports whether target is the Kind of e.
+func (e *RenderError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// newExecError builds the RenderError for err returned from executing entry in
+// the resolved layout, extracting the failing template and its location.
+func newExecError(layout string, resolved string, entry string, err error) *RenderError {
+	re := &RenderError{Kind: ErrExec, Layout: layout, Resolved: resolved, Entry: entry, Err: err}
+
+	var escapeErr *htmltemplate.Error
+	if errors.As(err, &escapeErr) {
+		re.Template = escapeErr.Name
+		re.Line = escapeErr.Line
+		return re
+	}
+
+	re.Template, re.Line, re.Col = parseErrorLocation(err)
+
+	var execErr texttemplate.ExecError
+	if re.Template == "" && errors.As(err, &execErr) {
+		re.Template = execErr.Name
+	}
+
+	return re
+}
+
+// templateErrorLocation matches the location prefix of errors from the
+// text/template parser and executor, e.g. "template: name:12: ..." or
+// "template: name:12:5: ...".
+var templateErrorLocation = regexp.MustCompile(`template: ([^:]*):(\d+)(?::(\d+))?: `)
+
+// parseErrorPosition extracts the line and column from a template error
+// message. Either is zero when not present.
+func parseErrorPosition(err error) (line int, col int) {
+	_, line, col = parseErrorLocation(err)
+	return line, col
+}
+
+// parseErrorLocation extracts the template name, line and column from a
+// template error message. They are empty or zero when not present.
+func parseErrorLocation(err error) (name string, line int, col int) {
+	m := templateErrorLocation.FindStringSubmatch(err.Error())
+	if m == nil {
+		return "", 0, 0
+	}
+
+	line, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		col, _ = strconv.Atoi(m[3])
+	}
+	return m[1], line, col
+}
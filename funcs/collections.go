@@ -0,0 +1,842 @@
+package funcs
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MAX_SEQ_LEN is the longest sequence Seq returns.
+const MAX_SEQ_LEN = 10000
+
+// Group is a group of the items of a collection with the same key, see
+// GroupBy. Items is a slice of the collection's element type.
+type Group struct {
+	Key   interface{}
+	Items interface{}
+}
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	timeType  = reflect.TypeOf(time.Time{})
+
+	shuffleMu   sync.Mutex
+	shuffleRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Where returns the items of collection, a slice, array or map, whose key
+// compares to a value. The comparison is equality, or the operator given
+// before the value:
+//
+//	where .Products "Category" "pens"
+//	where .Products "Price" ">=" 100
+//	where .Products "Tags" "in" (list "ink" "paper")
+//
+// The operators are "=" ("==", "eq"), "!=" ("<>", "ne"), "<" ("lt"), "<="
+// ("le"), ">" ("gt"), ">=" ("ge"), "in" and "not in". A key holding a slice is
+// "in" the value when any of its items is. key names a struct field,
+// a method without arguments or a map key, and may be a path such as
+// "Params.color". An empty key or "." compares the items themselves. Items of
+// a slice are returned as a slice of the same element type, the entries of a
+// map as a map.
+func Where(collection interface{}, key string, args ...interface{}) (interface{}, error) {
+	op, value := "=", interface{}(nil)
+	switch len(args) {
+	case 1:
+		value = args[0]
+	case 2:
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("operator must be a string, got %T", args[0])
+		}
+		op, value = s, args[1]
+	default:
+		return nil, fmt.Errorf("expected a value, or an operator and a value, got %d arguments", len(args))
+	}
+
+	match := func(item reflect.Value) (bool, error) {
+		v, err := property(item, key)
+		if err != nil {
+			return false, err
+		}
+		return compare(v, reflect.ValueOf(value), op)
+	}
+
+	seq := indirect(reflect.ValueOf(collection))
+	switch seq.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Slice, reflect.Array:
+		out := reflect.MakeSlice(reflect.SliceOf(seq.Type().Elem()), 0, seq.Len())
+		for i := 0; i < seq.Len(); i++ {
+			ok, err := match(seq.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				out = reflect.Append(out, seq.Index(i))
+			}
+		}
+		return out.Interface(), nil
+	case reflect.Map:
+		out := reflect.MakeMap(seq.Type())
+		iter := seq.MapRange()
+		for iter.Next() {
+			ok, err := match(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				out.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+		return out.Interface(), nil
+	}
+
+	return nil, fmt.Errorf("can't iterate over %T", collection)
+}
+
+// SortBy returns the items of collection, a slice, array or map, sorted by
+// key, see Where for keys. The optional order is "asc", the default, or
+// "desc":
+//
+//	sortBy .Tags
+//	sortBy .Products "Price" "desc"
+//
+// The sort is stable, items with equal keys keep their order. The values of a
+// map are sorted, starting from the order of its keys. Items without the key
+// sort first.
+func SortBy(collection interface{}, args ...string) (interface{}, error) {
+	key, order := "", "asc"
+	switch len(args) {
+	case 2:
+		order = args[1]
+		fallthrough
+	case 1:
+		key = args[0]
+	case 0:
+	default:
+		return nil, fmt.Errorf("expected a key and an order, got %d arguments", len(args))
+	}
+	if order != "asc" && order != "desc" {
+		return nil, fmt.Errorf("order must be \"asc\" or \"desc\", got %q", order)
+	}
+
+	items, elem, err := values(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]reflect.Value, len(items))
+	for i, item := range items {
+		if keys[i], err = property(item, key); err != nil {
+			return nil, err
+		}
+	}
+
+	idx := make([]int, len(items))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		a, b := keys[idx[i]], keys[idx[j]]
+		if order == "desc" {
+			a, b = b, a
+		}
+		c, cmpErr := order3(a, b)
+		if cmpErr != nil && err == nil {
+			err = cmpErr
+		}
+		return c < 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := reflect.MakeSlice(reflect.SliceOf(elem), 0, len(items))
+	for _, i := range idx {
+		out = reflect.Append(out, items[i])
+	}
+
+	return out.Interface(), nil
+}
+
+// GroupBy groups the items of collection, a slice, array or map, by key, see
+// Where for keys. The groups are in the order their key first appears:
+//
+//	{{ range groupBy .Products "Category" }}
+//	  <h2>{{ .Key }}</h2>
+//	  {{ range .Items }}...{{ end }}
+//	{{ end }}
+func GroupBy(collection interface{}, key string) ([]Group, error) {
+	items, elem, err := values(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []Group
+	var members []reflect.Value
+	index := make(map[interface{}]int)
+
+	for _, item := range items {
+		k, err := property(item, key)
+		if err != nil {
+			return nil, err
+		}
+
+		var groupKey interface{}
+		if k = indirect(k); k.IsValid() {
+			if !k.Type().Comparable() {
+				return nil, fmt.Errorf("can't group by %q, its values of type %s are not comparable", key, k.Type())
+			}
+			groupKey = k.Interface()
+		}
+
+		i, ok := index[groupKey]
+		if !ok {
+			i = len(groups)
+			index[groupKey] = i
+			groups = append(groups, Group{Key: groupKey})
+			members = append(members, reflect.MakeSlice(reflect.SliceOf(elem), 0, 1))
+		}
+		members[i] = reflect.Append(members[i], item)
+	}
+
+	for i := range groups {
+		groups[i].Items = members[i].Interface()
+	}
+
+	return groups, nil
+}
+
+// First returns the first limit items of collection, a slice or array, or all
+// of them when there are fewer:
+//
+//	range first 3 .Products
+func First(limit int, collection interface{}) (interface{}, error) {
+	seq, err := sliceOf(collection)
+	if err != nil || !seq.IsValid() {
+		return nil, err
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative, got %d", limit)
+	}
+	if limit > seq.Len() {
+		limit = seq.Len()
+	}
+
+	return seq.Slice(0, limit).Interface(), nil
+}
+
+// Last returns the last limit items of collection, a slice or array, or all of
+// them when there are fewer.
+func Last(limit int, collection interface{}) (interface{}, error) {
+	seq, err := sliceOf(collection)
+	if err != nil || !seq.IsValid() {
+		return nil, err
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative, got %d", limit)
+	}
+	if limit > seq.Len() {
+		limit = seq.Len()
+	}
+
+	return seq.Slice(seq.Len()-limit, seq.Len()).Interface(), nil
+}
+
+// After returns the items of collection, a slice or array, after the first
+// index ones, e.g. the rest of a list whose first item is featured:
+//
+//	range after 1 .Products
+func After(index int, collection interface{}) (interface{}, error) {
+	seq, err := sliceOf(collection)
+	if err != nil || !seq.IsValid() {
+		return nil, err
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("index must not be negative, got %d", index)
+	}
+	if index > seq.Len() {
+		index = seq.Len()
+	}
+
+	return seq.Slice(index, seq.Len()).Interface(), nil
+}
+
+// Uniq returns the items of collection, a slice or array, without repeats,
+// keeping the first of equal items.
+func Uniq(collection interface{}) (interface{}, error) {
+	seq, err := sliceOf(collection)
+	if err != nil || !seq.IsValid() {
+		return nil, err
+	}
+
+	out := reflect.MakeSlice(seq.Type(), 0, seq.Len())
+	seen := make(map[interface{}]bool)
+	var others []interface{} // items that can't be map keys
+
+	for i := 0; i < seq.Len(); i++ {
+		item := seq.Index(i)
+		v := indirectInterface(item)
+
+		if !v.IsValid() || v.Type().Comparable() {
+			var key interface{}
+			if v.IsValid() {
+				key = v.Interface()
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		} else {
+			dup := false
+			for _, other := range others {
+				if reflect.DeepEqual(other, v.Interface()) {
+					dup = true
+					break
+				}
+			}
+			if dup {
+				continue
+			}
+			others = append(others, v.Interface())
+		}
+
+		out = reflect.Append(out, item)
+	}
+
+	return out.Interface(), nil
+}
+
+// In reports whether collection, a slice or array, holds an item equal to
+// value, or whether the string collection contains the string value.
+func In(collection interface{}, value interface{}) (bool, error) {
+	seq := indirect(reflect.ValueOf(collection))
+	switch seq.Kind() {
+	case reflect.Invalid:
+		return false, nil
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return false, fmt.Errorf("can't look for %T in a string", value)
+		}
+		return strings.Contains(seq.String(), s), nil
+	case reflect.Slice, reflect.Array:
+		v := reflect.ValueOf(value)
+		for i := 0; i < seq.Len(); i++ {
+			if equal(seq.Index(i), v) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	return false, fmt.Errorf("can't look for a value in %T", collection)
+}
+
+// Shuffle returns the items of collection, a slice or array, in a random
+// order.
+func Shuffle(collection interface{}) (interface{}, error) {
+	seq, err := sliceOf(collection)
+	if err != nil || !seq.IsValid() {
+		return nil, err
+	}
+
+	out := reflect.MakeSlice(seq.Type(), seq.Len(), seq.Len())
+	reflect.Copy(out, seq)
+
+	swap := reflect.Swapper(out.Interface())
+	shuffleMu.Lock()
+	shuffleRand.Shuffle(out.Len(), swap)
+	shuffleMu.Unlock()
+
+	return out.Interface(), nil
+}
+
+// Seq returns a sequence of integers:
+//
+//	seq 3       1 2 3
+//	seq -3      -1 -2 -3
+//	seq 2 4     2 3 4
+//	seq 10 5 20 10 15 20
+//
+// The last number is included when the sequence reaches it. Sequences are at
+// most MAX_SEQ_LEN numbers long.
+func Seq(args ...int) ([]int, error) {
+	var first, inc, last int
+	switch len(args) {
+	case 1:
+		first, inc, last = 1, 1, args[0]
+		if last < 0 {
+			first, inc = -1, -1
+		}
+		if last == 0 {
+			return []int{}, nil
+		}
+	case 2:
+		first, inc, last = args[0], 1, args[1]
+		if last < first {
+			inc = -1
+		}
+	case 3:
+		first, inc, last = args[0], args[1], args[2]
+		if inc == 0 || inc > 0 && last < first || inc < 0 && last > first {
+			return nil, fmt.Errorf("increment %d never gets from %d to %d", inc, first, last)
+		}
+	default:
+		return nil, fmt.Errorf("expected 1 to 3 arguments, got %d", len(args))
+	}
+
+	// The distance and step are counted unsigned, so that neither overflows
+	// for numbers far apart.
+	span, step := uint64(last)-uint64(first), uint64(inc)
+	if inc < 0 {
+		span, step = uint64(first)-uint64(last), -uint64(inc)
+	}
+	if span/step >= MAX_SEQ_LEN {
+		return nil, fmt.Errorf("sequence from %d to %d is longer than %d numbers", first, last, MAX_SEQ_LEN)
+	}
+	n := int(span/step) + 1
+
+	out := make([]int, n)
+	for i := range out {
+		out[i] = first + i*inc
+	}
+
+	return out, nil
+}
+
+// Dict returns a map of the key and value pairs given, for passing several
+// values to a template:
+//
+//	template "product-card.html.tmpl" dict "Product" . "Featured" true
+func Dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("expected key and value pairs, got an odd number of arguments")
+	}
+
+	out := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("keys must be strings, got %T", pairs[i])
+		}
+		out[key] = pairs[i+1]
+	}
+
+	return out, nil
+}
+
+// List returns its arguments as a slice. It is not named slice, which is the
+// text/template builtin that slices a value.
+func List(items ...interface{}) []interface{} {
+	return append([]interface{}{}, items...)
+}
+
+// Append returns collection, the last argument, with the items before it
+// appended, so that it can be piped:
+//
+//	$tags = $tags | append "ink" "paper"
+//
+// A single slice item appends each of its items. The result has the slice
+// type of collection when every item is assignable to its elements, and is a
+// []interface{} otherwise.
+func Append(args ...interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, errors.New("expected items to append and a collection")
+	}
+
+	items := make([]reflect.Value, 0, len(args)-1)
+	for _, item := range args[:len(args)-1] {
+		items = append(items, reflect.ValueOf(item))
+	}
+	if len(items) == 1 {
+		if v := indirect(items[0]); v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+			items = items[:0]
+			for i := 0; i < v.Len(); i++ {
+				items = append(items, v.Index(i))
+			}
+		}
+	}
+
+	seq, err := sliceOf(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	typ := reflect.TypeOf([]interface{}{})
+	if seq.IsValid() {
+		typ = seq.Type()
+		for _, item := range items {
+			if !item.IsValid() || !item.Type().AssignableTo(typ.Elem()) {
+				typ = reflect.TypeOf([]interface{}{})
+				break
+			}
+		}
+	}
+
+	out := reflect.MakeSlice(typ, 0, len(items))
+	if seq.IsValid() {
+		for i := 0; i < seq.Len(); i++ {
+			out = reflect.Append(out, seq.Index(i))
+		}
+	}
+	for _, item := range items {
+		if !item.IsValid() {
+			item = reflect.Zero(typ.Elem())
+		}
+		out = reflect.Append(out, item)
+	}
+
+	return out.Interface(), nil
+}
+
+// Merge returns the maps merged from left to right: a key takes the value of
+// the last map that has it, and nested maps are merged the same way. The maps
+// must have string keys and are not modified.
+func Merge(maps ...interface{}) (map[string]interface{}, error) {
+	if len(maps) == 0 {
+		return nil, errors.New("expected maps to merge")
+	}
+
+	out := make(map[string]interface{})
+	for _, m := range maps {
+		v := indirect(reflect.ValueOf(m))
+		if !v.IsValid() {
+			continue
+		}
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("can't merge %T, expected a map with string keys", m)
+		}
+		mergeInto(out, v)
+	}
+
+	return out, nil
+}
+
+func mergeInto(dst map[string]interface{}, src reflect.Value) {
+	iter := src.MapRange()
+	for iter.Next() {
+		key := iter.Key().String()
+		value := indirectInterface(iter.Value())
+
+		if isStringMap(value) {
+			nested, ok := dst[key].(map[string]interface{})
+			if !ok {
+				nested = make(map[string]interface{})
+			}
+			mergeInto(nested, value)
+			dst[key] = nested
+			continue
+		}
+
+		if value.IsValid() {
+			dst[key] = value.Interface()
+		} else {
+			dst[key] = nil
+		}
+	}
+}
+
+func isStringMap(v reflect.Value) bool {
+	return v.IsValid() && v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String
+}
+
+// values returns the items of collection, a slice, array or map, and their
+// type. The values of a map are ordered by key.
+func values(collection interface{}) ([]reflect.Value, reflect.Type, error) {
+	seq := indirect(reflect.ValueOf(collection))
+	switch seq.Kind() {
+	case reflect.Invalid:
+		return nil, reflect.TypeOf((*interface{})(nil)).Elem(), nil
+	case reflect.Slice, reflect.Array:
+		items := make([]reflect.Value, seq.Len())
+		for i := range items {
+			items[i] = seq.Index(i)
+		}
+		return items, seq.Type().Elem(), nil
+	case reflect.Map:
+		keys := seq.MapKeys()
+		var err error
+		sort.SliceStable(keys, func(i, j int) bool {
+			c, cmpErr := order3(keys[i], keys[j])
+			if cmpErr != nil && err == nil {
+				err = cmpErr
+			}
+			return c < 0
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		items := make([]reflect.Value, len(keys))
+		for i, key := range keys {
+			items[i] = seq.MapIndex(key)
+		}
+		return items, seq.Type().Elem(), nil
+	}
+
+	return nil, nil, fmt.Errorf("can't iterate over %T", collection)
+}
+
+// sliceOf returns collection as a slice, copying an array, or an invalid Value
+// for nil.
+func sliceOf(collection interface{}) (reflect.Value, error) {
+	seq := indirect(reflect.ValueOf(collection))
+	switch seq.Kind() {
+	case reflect.Invalid, reflect.Slice:
+		return seq, nil
+	case reflect.Array:
+		out := reflect.MakeSlice(reflect.SliceOf(seq.Type().Elem()), seq.Len(), seq.Len())
+		reflect.Copy(out, seq)
+		return out, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("expected a slice or array, got %T", collection)
+}
+
+// indirect follows pointers and interfaces to a value, returning an invalid
+// Value for nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// indirectInterface unwraps interfaces, returning an invalid Value for nil.
+func indirectInterface(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// property returns the value at the dotted path key of item. A missing map
+// key or a nil value on the way gives an invalid Value.
+func property(item reflect.Value, key string) (reflect.Value, error) {
+	if key == "" || key == "." {
+		return indirectInterface(item), nil
+	}
+
+	v := item
+	for _, name := range strings.Split(strings.TrimPrefix(key, "."), ".") {
+		v = indirectInterface(v)
+		if !v.IsValid() {
+			return v, nil
+		}
+
+		// Pointer methods are found on addressable values, such as the
+		// items of a slice, as text/template does.
+		recv := v
+		if recv.Kind() != reflect.Ptr && recv.CanAddr() {
+			recv = recv.Addr()
+		}
+		if m := recv.MethodByName(name); m.IsValid() {
+			var err error
+			if v, err = call(m, name); err != nil {
+				return reflect.Value{}, err
+			}
+			continue
+		}
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return reflect.Value{}, nil
+		}
+		v = indirect(v)
+
+		switch v.Kind() {
+		case reflect.Struct:
+			f, ok := v.Type().FieldByName(name)
+			if !ok {
+				return reflect.Value{}, fmt.Errorf("%s has no field or method %q", v.Type(), name)
+			}
+			if f.PkgPath != "" {
+				return reflect.Value{}, fmt.Errorf("field %q of %s is unexported", name, v.Type())
+			}
+			v = v.FieldByIndex(f.Index)
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, fmt.Errorf("can't look up %q in %s, its keys are not strings", name, v.Type())
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		default:
+			return reflect.Value{}, fmt.Errorf("can't look up %q in %s", name, v.Type())
+		}
+	}
+
+	return indirectInterface(v), nil
+}
+
+// call calls the method m without arguments, which returns a value and
+// optionally an error.
+func call(m reflect.Value, name string) (reflect.Value, error) {
+	t := m.Type()
+	if t.NumIn() != 0 || t.NumOut() == 0 || t.NumOut() > 2 || (t.NumOut() == 2 && t.Out(1) != errorType) {
+		return reflect.Value{}, fmt.Errorf("method %q must take no arguments and return a value and optionally an error", name)
+	}
+
+	out := m.Call(nil)
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, out[1].Interface().(error)
+	}
+
+	return out[0], nil
+}
+
+// compare reports whether a and b compare with op, see Where.
+func compare(a, b reflect.Value, op string) (bool, error) {
+	switch op {
+	case "=", "==", "eq":
+		return equal(a, b), nil
+	case "!=", "<>", "ne":
+		return !equal(a, b), nil
+	case "in", "not in":
+		in, err := intersects(a, b)
+		if err != nil {
+			return false, err
+		}
+		return in == (op == "in"), nil
+	case "<", "lt", "<=", "le", ">", "gt", ">=", "ge":
+		a, b = indirect(a), indirect(b)
+		if !a.IsValid() || !b.IsValid() {
+			return false, nil
+		}
+		c, err := order3(a, b)
+		if err != nil {
+			return false, err
+		}
+		switch op {
+		case "<", "lt":
+			return c < 0, nil
+		case "<=", "le":
+			return c <= 0, nil
+		case ">", "gt":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	}
+
+	return false, fmt.Errorf("unknown operator %q", op)
+}
+
+// intersects reports whether a is in the collection b or, when a is a slice
+// or array itself, whether any of its items is.
+func intersects(a, b reflect.Value) (bool, error) {
+	a = indirect(a)
+	if !a.IsValid() {
+		return false, nil
+	}
+	if a.Kind() != reflect.Slice && a.Kind() != reflect.Array {
+		return In(interfaceOf(b), interfaceOf(a))
+	}
+
+	for i := 0; i < a.Len(); i++ {
+		in, err := In(interfaceOf(b), interfaceOf(a.Index(i)))
+		if err != nil || in {
+			return in, err
+		}
+	}
+
+	return false, nil
+}
+
+// equal reports whether a and b are equal, comparing numbers of any type by
+// value.
+func equal(a, b reflect.Value) bool {
+	a, b = indirect(a), indirect(b)
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return a.String() == b.String()
+	}
+	if a.Type() == timeType && b.Type() == timeType {
+		return a.Interface().(time.Time).Equal(b.Interface().(time.Time))
+	}
+	if !a.CanInterface() || !b.CanInterface() {
+		return false
+	}
+
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// order3 returns -1, 0 or 1 as a sorts before, with or after b. Numbers,
+// strings and times can be ordered, invalid values sort first.
+func order3(a, b reflect.Value) (int, error) {
+	a, b = indirect(a), indirect(b)
+	switch {
+	case !a.IsValid() && !b.IsValid():
+		return 0, nil
+	case !a.IsValid():
+		return -1, nil
+	case !b.IsValid():
+		return 1, nil
+	}
+
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return strings.Compare(a.String(), b.String()), nil
+	}
+	if a.Type() == timeType && b.Type() == timeType {
+		x, y := a.Interface().(time.Time), b.Interface().(time.Time)
+		switch {
+		case x.Before(y):
+			return -1, nil
+		case x.After(y):
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	return 0, fmt.Errorf("can't order %s and %s", a.Type(), b.Type())
+}
+
+// number returns the value of an integer or floating point v.
+func number(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+
+	return 0, false
+}
+
+func interfaceOf(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+
+	return v.Interface()
+}
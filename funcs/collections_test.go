@@ -0,0 +1,147 @@
+package funcs_test
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ukiahsmith/lemur/funcs"
+)
+
+type product struct {
+	Name     string
+	Category string
+	Price    float64
+	Stock    int
+	Added    time.Time
+	Tags     []string
+	Params   map[string]interface{}
+}
+
+func (p *product) InStock() bool {
+	return p.Stock > 0
+}
+
+func TestCollections(t *testing.T) {
+	products := []product{
+		{Name: "M800", Category: "pens", Price: 495, Stock: 2, Added: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), Tags: []string{"gold", "piston"}, Params: map[string]interface{}{"color": "blue"}},
+		{Name: "Ink", Category: "ink", Price: 18.5, Stock: 0, Added: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Tags: []string{"ink", "bottle"}, Params: map[string]interface{}{"color": "black"}},
+		{Name: "Safari", Category: "pens", Price: 29, Stock: 10, Added: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "Pad", Category: "paper", Price: 12, Stock: 4, Added: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC), Tags: []string{"paper"}, Params: map[string]interface{}{"color": "white"}},
+	}
+	data := map[string]interface{}{
+		"Products": products,
+		"Tags":     []string{"ink", "pens", "ink", "paper", "pens"},
+		"Colors":   map[string]string{"b": "blue", "a": "red", "c": "green"},
+		"Items":    []map[string]interface{}{{"id": 2, "sku": "b"}, {"id": 1, "sku": "a"}, {"id": 3}},
+	}
+
+	testCases := []struct {
+		Name     string
+		Tmpl     string
+		Expected string
+	}{
+		{"where equal", `{{ range where .Products "Category" "pens" }}{{ .Name }} {{ end }}`, "M800 Safari "},
+		{"where operator", `{{ range where .Products "Price" ">=" 29 }}{{ .Name }} {{ end }}`, "M800 Safari "},
+		{"where not equal", `{{ range where .Products "Category" "ne" "pens" }}{{ .Name }} {{ end }}`, "Ink Pad "},
+		{"where in", `{{ range where .Products "Category" "in" (list "ink" "paper") }}{{ .Name }} {{ end }}`, "Ink Pad "},
+		{"where not in", `{{ range where .Products "Name" "not in" (list "Ink" "Pad") }}{{ .Name }} {{ end }}`, "M800 Safari "},
+		{"where slice in", `{{ range where .Products "Tags" "in" (list "ink" "paper") }}{{ .Name }} {{ end }}`, "Ink Pad "},
+		{"where slice not in", `{{ range where .Products "Tags" "not in" (list "ink" "paper") }}{{ .Name }} {{ end }}`, "M800 Safari "},
+		{"where method", `{{ range where .Products "InStock" false }}{{ .Name }} {{ end }}`, "Ink "},
+		{"where path", `{{ range where .Products "Params.color" "blue" }}{{ .Name }} {{ end }}`, "M800 "},
+		{"where time", `{{ range where .Products "Added" "<" (index .Products 2).Added }}{{ .Name }} {{ end }}`, "Ink "},
+		{"where map items", `{{ range where .Items "id" ">" 1 }}{{ .id }} {{ end }}`, "2 3 "},
+		{"where map", `{{ range $k, $v := where .Colors "." "!=" "red" }}{{ $k }}={{ $v }} {{ end }}`, "b=blue c=green "},
+		{"sortBy values", `{{ range sortBy .Tags }}{{ . }} {{ end }}`, "ink ink paper pens pens "},
+		{"sortBy key", `{{ range sortBy .Products "Price" }}{{ .Name }} {{ end }}`, "Pad Ink Safari M800 "},
+		{"sortBy descending", `{{ range sortBy .Products "Added" "desc" }}{{ .Name }} {{ end }}`, "Pad M800 Safari Ink "},
+		{"sortBy stable", `{{ range sortBy .Products "Category" }}{{ .Name }} {{ end }}`, "Ink Pad M800 Safari "},
+		{"sortBy missing keys first", `{{ range sortBy .Items "sku" }}{{ .id }} {{ end }}`, "3 1 2 "},
+		{"sortBy map", `{{ range sortBy .Colors }}{{ . }} {{ end }}`, "blue green red "},
+		{"groupBy", `{{ range groupBy .Products "Category" }}{{ .Key }}:{{ range .Items }} {{ .Name }}{{ end }}; {{ end }}`, "pens: M800 Safari; ink: Ink; paper: Pad; "},
+		{"first", `{{ range first 2 .Products }}{{ .Name }} {{ end }}`, "M800 Ink "},
+		{"first more than all", `{{ len (first 10 .Tags) }}`, "5"},
+		{"last", `{{ range last 2 .Products }}{{ .Name }} {{ end }}`, "Safari Pad "},
+		{"after", `{{ range after 3 .Products }}{{ .Name }} {{ end }}`, "Pad "},
+		{"after all", `{{ len (after 9 .Products) }}`, "0"},
+		{"uniq", `{{ uniq .Tags }}`, "[ink pens paper]"},
+		{"uniq interfaces", `{{ uniq (list 1 "1" 1 (dict "a" 1) (dict "a" 1)) }}`, "[1 1 map[a:1]]"},
+		{"in slice", `{{ in .Tags "paper" }} {{ in .Tags "quill" }}`, "true false"},
+		{"in numbers", `{{ in (seq 5) 3 }}`, "true"},
+		{"in string", `{{ in "fountain pen" "pen" }}`, "true"},
+		{"shuffle keeps items", `{{ len (shuffle .Tags) }} {{ sortBy (shuffle .Tags) }}`, "5 [ink ink paper pens pens]"},
+		{"seq", `{{ seq 3 }} {{ seq -2 }} {{ seq 2 4 }} {{ seq 4 2 }} {{ seq 10 5 20 }} {{ seq 0 }}`, "[1 2 3] [-1 -2] [2 3 4] [4 3 2] [10 15 20] []"},
+		{"seq extremes", `{{ seq 9223372036854775807 -9223372036854775808 -9223372036854775808 }}`, "[9223372036854775807 -1]"},
+		{"dict", `{{ with dict "name" "M800" "price" 495 }}{{ .name }} {{ .price }}{{ end }}`, "M800 495"},
+		{"list", `{{ list "a" 1 true }}`, "[a 1 true]"},
+		{"builtin slice", `{{ slice (seq 3) 1 2 }} {{ slice "lemur" 1 }}`, "[2] emur"},
+		{"append", `{{ .Tags | append "quill" }}`, "[ink pens ink paper pens quill]"},
+		{"append slice", `{{ append (list "quill" "nib") (first 1 .Tags) }}`, "[ink quill nib]"},
+		{"append mixed", `{{ append 1 (list "a") }}`, "[a 1]"},
+		{"merge", `{{ merge (dict "a" 1 "n" (dict "x" 1 "y" 2)) (dict "b" 2 "n" (dict "y" 3)) }}`, "map[a:1 b:2 n:map[x:1 y:3]]"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tmpl, err := template.New(tc.Name).Funcs(funcs.DefaultFuncMap()).Parse(tc.Tmpl)
+			if err != nil {
+				t.Fatalf("Failed to parse template: %s", err)
+			}
+
+			var buf strings.Builder
+			if err := tmpl.Execute(&buf, data); err != nil {
+				t.Fatalf("Failed to execute template: %s", err)
+			}
+
+			if buf.String() != tc.Expected {
+				t.Errorf("Expected %q, but got %q", tc.Expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestCollections_Errors(t *testing.T) {
+	data := map[string]interface{}{
+		"Products": []product{{Name: "M800"}, {Name: "Ink"}},
+		"Tags":     []string{"ink"},
+	}
+
+	testCases := []struct {
+		Name     string
+		Tmpl     string
+		Expected string
+	}{
+		{"where unknown field", `{{ where .Products "Colour" "blue" }}`, `has no field or method "Colour"`},
+		{"where unknown operator", `{{ where .Products "Name" "~" "M" }}`, `unknown operator "~"`},
+		{"where not a collection", `{{ where 42 "Name" "M" }}`, "can't iterate over int"},
+		{"where unordered", `{{ where .Products "Params" ">" 1 }}`, "can't order"},
+		{"sortBy order", `{{ sortBy .Products "Name" "up" }}`, `order must be "asc" or "desc"`},
+		{"groupBy uncomparable", `{{ groupBy .Products "Params" }}`, "not comparable"},
+		{"first negative", `{{ first -1 .Tags }}`, "limit must not be negative"},
+		{"first of a map", `{{ first 1 (dict "a" 1) }}`, "expected a slice or array"},
+		{"seq too long", `{{ seq 20000 }}`, "longer than 10000"},
+		{"seq overflow", `{{ seq -9223372036854775808 }}`, "longer than 10000"},
+		{"seq overflow span", `{{ seq -9223372036854775808 9223372036854775807 }}`, "longer than 10000"},
+		{"seq wrong direction", `{{ seq 1 -1 5 }}`, "never gets from 1 to 5"},
+		{"dict odd", `{{ dict "a" }}`, "odd number of arguments"},
+		{"dict key", `{{ dict 1 2 }}`, "keys must be strings"},
+		{"append nothing", `{{ append .Tags }}`, "expected items to append and a collection"},
+		{"merge not a map", `{{ merge (dict "a" 1) .Tags }}`, "can't merge []string"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tmpl, err := template.New(tc.Name).Funcs(funcs.DefaultFuncMap()).Parse(tc.Tmpl)
+			if err != nil {
+				t.Fatalf("Failed to parse template: %s", err)
+			}
+
+			err = tmpl.Execute(&strings.Builder{}, data)
+			if err == nil || !strings.Contains(err.Error(), tc.Expected) {
+				t.Errorf("Expected an error containing %q, but got %v", tc.Expected, err)
+			}
+		})
+	}
+}
@@ -1,12 +1,63 @@
+// Package funcs provides the default template function library that lemur
+// makes available to every theme.
 package funcs
 
 import "html/template"
 
+// DefaultFuncMap returns the template functions registered by default:
+//
+//	absURL       AbsURL, join a path onto a base *url.URL
+//	mod          Mod, integer modulo
+//	modBool      ModBool, report whether an integer modulo is zero
+//	safeHTML     SafeHTML, mark a trusted string as HTML
+//	markdown     MarkdownRenderer.Render, render Markdown to HTML, omitting raw HTML
+//	sanitizeHTML Sanitizer.Sanitize, remove HTML not allowed by UGCPolicy
+//	dateFormat   DateFormat, format a date in the local time zone
+//	dateFormatIn DateFormatIn, format a date in a named time zone
+//	where        Where, filter a collection by a key
+//	sortBy       SortBy, sort a collection by a key
+//	groupBy      GroupBy, group a collection by a key
+//	first        First, the first items of a slice
+//	last         Last, the last items of a slice
+//	after        After, the items of a slice after the first ones
+//	uniq         Uniq, a slice without repeated items
+//	in           In, report whether a slice or string holds a value
+//	shuffle      Shuffle, a slice in random order
+//	seq          Seq, a sequence of integers
+//	dict         Dict, a map from key and value pairs
+//	list         List, a slice of the arguments
+//	append       Append, a slice with items appended
+//	merge        Merge, maps merged from left to right
+//
+// A new map is returned on every call, so callers may add to it freely.
 func DefaultFuncMap() template.FuncMap {
 	return template.FuncMap{
 		"absURL": AbsURL,
 
 		"mod":     Mod,
 		"modBool": ModBool,
+
+		"safeHTML": SafeHTML,
+		"markdown": DefaultMarkdownRenderer().Render,
+
+		"sanitizeHTML": DefaultSanitizer().Sanitize,
+
+		"dateFormat":   DateFormat,
+		"dateFormatIn": DateFormatIn,
+
+		"where":   Where,
+		"sortBy":  SortBy,
+		"groupBy": GroupBy,
+		"first":   First,
+		"last":    Last,
+		"after":   After,
+		"uniq":    Uniq,
+		"in":      In,
+		"shuffle": Shuffle,
+		"seq":     Seq,
+		"dict":    Dict,
+		"list":    List,
+		"append":  Append,
+		"merge":   Merge,
 	}
 }
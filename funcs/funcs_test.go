@@ -0,0 +1,97 @@
+package funcs_test
+
+import (
+	"html/template"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ukiahsmith/lemur/funcs"
+)
+
+func TestDefaultFuncMap(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com/shop")
+
+	testCases := []struct {
+		Name     string
+		Tmpl     string
+		Data     interface{}
+		Expected string
+	}{
+		{"absURL", `{{ absURL . "cart" }}`, baseURL, "https://example.com/shop/cart"},
+		{"mod", `{{ mod 7 3 }}`, nil, "1"},
+		{"modBool", `{{ modBool 6 3 }}`, nil, "true"},
+		{"safeHTML", `{{ safeHTML "<b>bold</b>" }}`, nil, "<b>bold</b>"},
+		{"markdown", `{{ markdown "# Title" }}`, nil, "<h1 id=\"title\">Title</h1>\n"},
+		{"sanitizeHTML", `{{ sanitizeHTML "<p onclick=x>ok</p>" }}`, nil, "<p>ok</p>"},
+		{"dateFormatIn", `{{ dateFormatIn . "2006-01-02 15:04" "UTC" }}`, time.Date(2021, 3, 4, 5, 6, 0, 0, time.UTC), "2021-03-04 05:06"},
+		{"dateFormat", `{{ dateFormat . "2006" }}`, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), "2021"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			tmpl, err := template.New(tc.Name).Funcs(funcs.DefaultFuncMap()).Parse(tc.Tmpl)
+			if err != nil {
+				t.Fatalf("Failed to parse template: %s", err)
+			}
+
+			var buf strings.Builder
+			if err := tmpl.Execute(&buf, tc.Data); err != nil {
+				t.Fatalf("Failed to execute template: %s", err)
+			}
+
+			if buf.String() != tc.Expected {
+				t.Errorf("Expected %q, but got %q", tc.Expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestDefaultFuncMap_Shared(t *testing.T) {
+	if funcs.DefaultMarkdownRenderer() != funcs.DefaultMarkdownRenderer() {
+		t.Errorf("Expected DefaultMarkdownRenderer to return the same renderer on every call")
+	}
+	if funcs.DefaultSanitizer() != funcs.DefaultSanitizer() {
+		t.Errorf("Expected DefaultSanitizer to return the same sanitizer on every call")
+	}
+}
+
+func TestSiteAbsURL(t *testing.T) {
+	siteURL, _ := url.Parse("https://example.com/shop")
+	otherURL, _ := url.Parse("https://cdn.example.com")
+
+	testCases := []struct {
+		Name     string
+		Base     *url.URL
+		Args     []interface{}
+		Expected string
+		Err      bool
+	}{
+		{"site default", siteURL, []interface{}{"cart"}, "https://example.com/shop/cart", false},
+		{"explicit base", siteURL, []interface{}{otherURL, "img/logo.png"}, "https://cdn.example.com/img/logo.png", false},
+		{"no site base", nil, []interface{}{"cart"}, "", true},
+		{"path not a string", siteURL, []interface{}{42}, "", true},
+		{"base not a url", siteURL, []interface{}{"https://cdn.example.com", "cart"}, "", true},
+		{"too many args", siteURL, []interface{}{siteURL, "a", "b"}, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			u, err := funcs.SiteAbsURL(tc.Base)(tc.Args...)
+			if tc.Err {
+				if err == nil {
+					t.Fatalf("Expected an error, but got %q", u)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, but got %s", err)
+			}
+
+			if u.String() != tc.Expected {
+				t.Errorf("Expected %q, but got %q", tc.Expected, u.String())
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package funcs
 
 import "html/template"
 
+// SafeHTML marks s as trusted HTML so that html/template outputs it without
+// escaping. It must only be used with content from a trusted source.
 func SafeHTML(s string) template.HTML {
 	return template.HTML(s)
 }
@@ -3,25 +3,158 @@ package funcs
 import (
 	"bytes"
 	"fmt"
+	"html/template"
+	"sync"
 
 	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer/html"
 )
 
-func Markdown(s string) string {
-	var buf bytes.Buffer
+// MarkdownRenderer converts Markdown to HTML. It is built once with the
+// selected extensions and is safe for concurrent use, so a single renderer can
+// back the "markdown" template func of every render.
+type MarkdownRenderer struct {
+	md        goldmark.Markdown
+	sanitizer *Sanitizer
+}
+
+// MarkdownOption selects an extension or behaviour of a MarkdownRenderer.
+type MarkdownOption func(*markdownConfig)
+
+type markdownConfig struct {
+	extensions []goldmark.Extender
+	unsafe     bool
+	sanitizer  *Sanitizer
+}
+
+// MarkdownGFM enables the GitHub Flavored Markdown extensions: tables,
+// strikethrough, autolinks and task lists.
+func MarkdownGFM() MarkdownOption {
+	return func(c *markdownConfig) {
+		c.extensions = append(c.extensions, extension.GFM)
+	}
+}
+
+// MarkdownTables enables GFM tables.
+func MarkdownTables() MarkdownOption {
+	return func(c *markdownConfig) {
+		c.extensions = append(c.extensions, extension.Table)
+	}
+}
+
+// MarkdownFootnotes enables PHP Markdown Extra style footnotes.
+func MarkdownFootnotes() MarkdownOption {
+	return func(c *markdownConfig) {
+		c.extensions = append(c.extensions, extension.Footnote)
+	}
+}
+
+// MarkdownTypographer replaces straight quotes, dashes and ellipses with their
+// typographic equivalents.
+func MarkdownTypographer() MarkdownOption {
+	return func(c *markdownConfig) {
+		c.extensions = append(c.extensions, extension.Typographer)
+	}
+}
+
+// MarkdownDefinitionLists enables PHP Markdown Extra style definition lists.
+func MarkdownDefinitionLists() MarkdownOption {
+	return func(c *markdownConfig) {
+		c.extensions = append(c.extensions, extension.DefinitionList)
+	}
+}
+
+// MarkdownUnsafeHTML passes raw HTML and potentially dangerous links in the
+// source through to the output. Without it raw HTML is omitted. Only enable it
+// for trusted content.
+func MarkdownUnsafeHTML() MarkdownOption {
+	return func(c *markdownConfig) {
+		c.unsafe = true
+	}
+}
+
+// MarkdownSanitize passes raw HTML in the source through, like
+// MarkdownUnsafeHTML, and then runs the rendered HTML through s. Use it to
+// render user supplied Markdown that may contain a safe subset of HTML.
+func MarkdownSanitize(s *Sanitizer) MarkdownOption {
+	return func(c *markdownConfig) {
+		c.unsafe = true
+		c.sanitizer = s
+	}
+}
+
+// defaultMarkdown is the renderer returned by DefaultMarkdownRenderer, built
+// on first use.
+var (
+	defaultMarkdownOnce sync.Once
+	defaultMarkdown     *MarkdownRenderer
+)
+
+// DefaultMarkdownRenderer returns the renderer built without options that
+// backs the "markdown" func of DefaultFuncMap. It is built once and shared.
+func DefaultMarkdownRenderer() *MarkdownRenderer {
+	defaultMarkdownOnce.Do(func() {
+		defaultMarkdown = NewMarkdownRenderer()
+	})
+
+	return defaultMarkdown
+}
 
-	if err := goldmark.New(
+// NewMarkdownRenderer builds a MarkdownRenderer with the given options.
+// Headings always get generated `id` attributes.
+func NewMarkdownRenderer(opts ...MarkdownOption) *MarkdownRenderer {
+	var c markdownConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	var rendererOpts []goldmark.Option
+	if c.unsafe {
+		rendererOpts = append(rendererOpts, goldmark.WithRendererOptions(html.WithUnsafe()))
+	}
+
+	md := goldmark.New(append(rendererOpts,
+		goldmark.WithExtensions(c.extensions...),
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(), // required to add `id` attributes to headers
 		),
-		goldmark.WithRendererOptions(
-			html.WithUnsafe(),
-		),
-	).Convert([]byte(s), &buf); err != nil {
-		fmt.Printf("error converting to markdown: %s", err)
+	)...)
+
+	return &MarkdownRenderer{md: md, sanitizer: c.sanitizer}
+}
+
+// Render converts the Markdown source s to HTML. The result is returned as
+// template.HTML so html/template does not escape it, and conversion errors are
+// returned so they fail the template execution. When the renderer was built
+// with MarkdownSanitize the output is sanitized first.
+func (m *MarkdownRenderer) Render(s string) (template.HTML, error) {
+	var buf bytes.Buffer
+
+	if err := m.md.Convert([]byte(s), &buf); err != nil {
+		return "", fmt.Errorf("error converting markdown: %w", err)
+	}
+
+	if m.sanitizer != nil {
+		return m.sanitizer.Sanitize(buf.String()), nil
+	}
+
+	return template.HTML(buf.String()), nil
+}
+
+// unsafeMarkdown backs the deprecated Markdown func.
+var unsafeMarkdown = NewMarkdownRenderer(MarkdownUnsafeHTML())
+
+// Markdown renders the Markdown source s to HTML. Raw HTML in the source is
+// passed through unchanged, and a conversion error results in an empty string.
+//
+// Deprecated: use a MarkdownRenderer, which is configurable and reports errors.
+func Markdown(s string) string {
+	out, err := unsafeMarkdown.Render(s)
+	if err != nil {
+		return ""
 	}
 
-	return buf.String()
+	return string(out)
 }
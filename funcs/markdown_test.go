@@ -0,0 +1,107 @@
+package funcs_test
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/ukiahsmith/lemur/funcs"
+)
+
+func TestMarkdownRenderer(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Options  []funcs.MarkdownOption
+		Source   string
+		Contains string
+		Excludes string
+	}{
+		{
+			Name:     "Heading ids",
+			Source:   "# A Title",
+			Contains: `<h1 id="a-title">A Title</h1>`,
+		},
+		{
+			Name:     "Raw HTML omitted by default",
+			Source:   "<script>alert(1)</script>",
+			Contains: "<!-- raw HTML omitted -->",
+			Excludes: "<script>",
+		},
+		{
+			Name:     "Raw HTML with unsafe",
+			Options:  []funcs.MarkdownOption{funcs.MarkdownUnsafeHTML()},
+			Source:   "<div>raw</div>",
+			Contains: "<div>raw</div>",
+		},
+		{
+			Name:     "Tables",
+			Options:  []funcs.MarkdownOption{funcs.MarkdownTables()},
+			Source:   "| a | b |\n|---|---|\n| 1 | 2 |",
+			Contains: "<table>",
+		},
+		{
+			Name:     "Tables not enabled",
+			Source:   "| a | b |\n|---|---|\n| 1 | 2 |",
+			Excludes: "<table>",
+		},
+		{
+			Name:     "GFM strikethrough",
+			Options:  []funcs.MarkdownOption{funcs.MarkdownGFM()},
+			Source:   "~~gone~~",
+			Contains: "<del>gone</del>",
+		},
+		{
+			Name:     "Footnotes",
+			Options:  []funcs.MarkdownOption{funcs.MarkdownFootnotes()},
+			Source:   "Text[^1]\n\n[^1]: The note.",
+			Contains: `class="footnotes"`,
+		},
+		{
+			Name:     "Typographer",
+			Options:  []funcs.MarkdownOption{funcs.MarkdownTypographer()},
+			Source:   `"quoted" -- text...`,
+			Contains: "&ldquo;quoted&rdquo; &ndash; text&hellip;",
+		},
+		{
+			Name:     "Definition lists",
+			Options:  []funcs.MarkdownOption{funcs.MarkdownDefinitionLists()},
+			Source:   "Term\n: Definition",
+			Contains: "<dl>\n<dt>Term</dt>\n<dd>Definition</dd>",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			out, err := funcs.NewMarkdownRenderer(tc.Options...).Render(tc.Source)
+			if err != nil {
+				t.Fatalf("Render failed: %s", err)
+			}
+
+			if tc.Contains != "" && !strings.Contains(string(out), tc.Contains) {
+				t.Errorf("Expected output to contain %q, but got %q", tc.Contains, out)
+			}
+			if tc.Excludes != "" && strings.Contains(string(out), tc.Excludes) {
+				t.Errorf("Expected output not to contain %q, but got %q", tc.Excludes, out)
+			}
+		})
+	}
+}
+
+func TestMarkdownRenderer_TemplateFunc(t *testing.T) {
+	md := funcs.NewMarkdownRenderer()
+
+	tmpl, err := template.New("md").Funcs(template.FuncMap{"markdown": md.Render}).Parse(`{{ markdown . }}`)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %s", err)
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, "*emphasis*"); err != nil {
+		t.Fatalf("Failed to execute template: %s", err)
+	}
+
+	expected := "<p><em>emphasis</em></p>\n"
+	if buf.String() != expected {
+		t.Errorf("Expected unescaped HTML %q, but got %q", expected, buf.String())
+	}
+}
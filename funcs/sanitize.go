@@ -0,0 +1,268 @@
+package funcs
+
+import (
+	"html/template"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// SanitizePolicy is the allow-list applied by a Sanitizer. Anything not
+// allowed is removed: disallowed elements are dropped while their text is
+// kept, and disallowed attributes are dropped from allowed elements.
+type SanitizePolicy struct {
+	// Tags maps each allowed element name to the attributes allowed on it, in
+	// addition to GlobalAttributes.
+	Tags map[string][]string
+
+	// GlobalAttributes are allowed on every allowed element.
+	GlobalAttributes []string
+
+	// URLSchemes are the schemes allowed in URL attributes such as href and
+	// src. Relative URLs are always allowed.
+	URLSchemes []string
+
+	// LinkRel, when not empty, is set as the rel attribute of every link with
+	// an href, e.g. "nofollow ugc". It replaces any rel from the input.
+	LinkRel string
+}
+
+// UGCPolicy returns a policy suitable for user generated content such as
+// Markdown written by sellers: text formatting, lists, tables, links and
+// images are allowed, only http, https and mailto URLs are accepted, and links
+// get rel="nofollow ugc".
+func UGCPolicy() SanitizePolicy {
+	return SanitizePolicy{
+		Tags: map[string][]string{
+			"a":          {"href", "title"},
+			"abbr":       nil,
+			"b":          nil,
+			"blockquote": {"cite"},
+			"br":         nil,
+			"code":       {"class"},
+			"dd":         nil,
+			"del":        nil,
+			"dl":         nil,
+			"dt":         nil,
+			"em":         nil,
+			"h1":         nil,
+			"h2":         nil,
+			"h3":         nil,
+			"h4":         nil,
+			"h5":         nil,
+			"h6":         nil,
+			"hr":         nil,
+			"i":          nil,
+			"img":        {"src", "alt", "width", "height"},
+			"input":      {"checked", "disabled", "type"},
+			"li":         nil,
+			"ol":         {"start"},
+			"p":          nil,
+			"pre":        nil,
+			"s":          nil,
+			"strong":     nil,
+			"sub":        nil,
+			"sup":        nil,
+			"table":      nil,
+			"tbody":      nil,
+			"td":         {"align"},
+			"th":         {"align"},
+			"thead":      nil,
+			"tr":         nil,
+			"ul":         nil,
+		},
+		GlobalAttributes: []string{"id", "title", "lang", "dir"},
+		URLSchemes:       []string{"http", "https", "mailto"},
+		LinkRel:          "nofollow ugc",
+	}
+}
+
+// urlAttributes are the attributes whose values are checked against the
+// allowed URL schemes.
+var urlAttributes = map[string]bool{
+	"action":     true,
+	"background": true,
+	"cite":       true,
+	"formaction": true,
+	"href":       true,
+	"longdesc":   true,
+	"poster":     true,
+	"src":        true,
+	"usemap":     true,
+}
+
+// droppedContent are the elements whose content is removed along with the
+// element itself when they are not allowed, rather than kept as text.
+var droppedContent = map[string]bool{
+	"iframe":   true,
+	"noembed":  true,
+	"noframes": true,
+	"noscript": true,
+	"object":   true,
+	"script":   true,
+	"style":    true,
+	"template": true,
+	"textarea": true,
+	"title":    true,
+	"xmp":      true,
+}
+
+// Sanitizer removes everything not allowed by its SanitizePolicy from HTML.
+// It is safe for concurrent use.
+type Sanitizer struct {
+	tags    map[string]map[string]bool
+	schemes map[string]bool
+	linkRel string
+}
+
+// defaultSanitizer is the sanitizer returned by DefaultSanitizer, built on
+// first use.
+var (
+	defaultSanitizerOnce sync.Once
+	defaultSanitizer     *Sanitizer
+)
+
+// DefaultSanitizer returns the UGCPolicy sanitizer that backs the
+// "sanitizeHTML" func of DefaultFuncMap. It is built once and shared.
+func DefaultSanitizer() *Sanitizer {
+	defaultSanitizerOnce.Do(func() {
+		defaultSanitizer = NewSanitizer(UGCPolicy())
+	})
+
+	return defaultSanitizer
+}
+
+// NewSanitizer compiles policy into a Sanitizer.
+func NewSanitizer(policy SanitizePolicy) *Sanitizer {
+	s := &Sanitizer{
+		tags:    make(map[string]map[string]bool, len(policy.Tags)),
+		schemes: make(map[string]bool, len(policy.URLSchemes)),
+		linkRel: policy.LinkRel,
+	}
+
+	for tag, attrs := range policy.Tags {
+		allowed := make(map[string]bool, len(attrs)+len(policy.GlobalAttributes))
+		for _, attr := range policy.GlobalAttributes {
+			allowed[strings.ToLower(attr)] = true
+		}
+		for _, attr := range attrs {
+			allowed[strings.ToLower(attr)] = true
+		}
+		s.tags[strings.ToLower(tag)] = allowed
+	}
+
+	for _, scheme := range policy.URLSchemes {
+		s.schemes[strings.ToLower(scheme)] = true
+	}
+
+	return s
+}
+
+// Sanitize returns the allowed subset of the HTML fragment in. The result is
+// returned as template.HTML so html/template outputs it without escaping.
+func (s *Sanitizer) Sanitize(in string) template.HTML {
+	var out strings.Builder
+
+	z := html.NewTokenizer(strings.NewReader(in))
+	skipDepth := 0 // nesting depth inside an element whose content is dropped
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			// io.EOF, a strings.Reader never fails otherwise.
+			break
+		}
+
+		token := z.Token()
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if skipDepth > 0 {
+				if tt == html.StartTagToken && droppedContent[token.Data] {
+					skipDepth++
+				}
+				continue
+			}
+
+			allowed, ok := s.tags[token.Data]
+			if !ok {
+				if tt == html.StartTagToken && droppedContent[token.Data] {
+					skipDepth++
+				}
+				continue
+			}
+
+			s.writeStartTag(&out, token, allowed)
+
+		case html.EndTagToken:
+			if skipDepth > 0 {
+				if droppedContent[token.Data] {
+					skipDepth--
+				}
+				continue
+			}
+
+			if _, ok := s.tags[token.Data]; ok {
+				out.WriteString("</" + token.Data + ">")
+			}
+
+		case html.TextToken:
+			if skipDepth > 0 {
+				continue
+			}
+			out.WriteString(html.EscapeString(token.Data))
+
+		case html.CommentToken, html.DoctypeToken:
+			// Always dropped.
+		}
+	}
+
+	return template.HTML(out.String())
+}
+
+// writeStartTag writes token with only its allowed attributes.
+func (s *Sanitizer) writeStartTag(out *strings.Builder, token html.Token, allowed map[string]bool) {
+	out.WriteString("<" + token.Data)
+
+	hasHref := false
+	for _, attr := range token.Attr {
+		key := attr.Key
+		if attr.Namespace != "" || !allowed[key] {
+			continue
+		}
+		if key == "rel" && s.linkRel != "" && token.Data == "a" {
+			continue
+		}
+		if urlAttributes[key] && !s.allowedURL(attr.Val) {
+			continue
+		}
+		if key == "href" {
+			hasHref = true
+		}
+
+		out.WriteString(" " + key + `="` + html.EscapeString(attr.Val) + `"`)
+	}
+
+	if token.Data == "a" && hasHref && s.linkRel != "" {
+		out.WriteString(` rel="` + html.EscapeString(s.linkRel) + `"`)
+	}
+
+	out.WriteString(">")
+}
+
+// allowedURL reports whether raw is a relative URL or uses an allowed scheme.
+func (s *Sanitizer) allowedURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		// A scheme hidden behind control characters fails to parse above, so a
+		// URL without a scheme here is relative.
+		return true
+	}
+
+	return s.schemes[strings.ToLower(u.Scheme)]
+}
@@ -0,0 +1,91 @@
+package funcs_test
+
+import (
+	"testing"
+
+	"github.com/ukiahsmith/lemur/funcs"
+)
+
+func TestSanitizer_UGCPolicy(t *testing.T) {
+	s := funcs.NewSanitizer(funcs.UGCPolicy())
+
+	testCases := []struct {
+		Name     string
+		Input    string
+		Expected string
+	}{
+		{"Allowed formatting", `<p><strong>bold</strong> <em>em</em></p>`, `<p><strong>bold</strong> <em>em</em></p>`},
+		{"Script removed with content", `a<script>alert(1)</script>b`, `ab`},
+		{"Style removed with content", `<style>p{}</style>text`, `text`},
+		{"Disallowed element keeps text", `<div><span>text</span></div>`, `text`},
+		{"Event handler attribute removed", `<p onclick="alert(1)">x</p>`, `<p>x</p>`},
+		{"Link gets rel", `<a href="https://example.com">x</a>`, `<a href="https://example.com" rel="nofollow ugc">x</a>`},
+		{"Existing rel replaced", `<a href="/p" rel="me">x</a>`, `<a href="/p" rel="nofollow ugc">x</a>`},
+		{"Link without href has no rel", `<a title="t">x</a>`, `<a title="t">x</a>`},
+		{"javascript URL removed", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"Obfuscated javascript URL removed", `<a href="jav&#x09;ascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"Mixed case scheme removed", `<a href=" JaVaScRiPt:alert(1)">x</a>`, `<a>x</a>`},
+		{"Image data URL removed", `<img src="data:image/png;base64,AAAA" alt="a">`, `<img alt="a">`},
+		{"Relative image kept", `<img src="/img/a.png" alt="a">`, `<img src="/img/a.png" alt="a">`},
+		{"Text is escaped", `1 &lt; 2 & 3`, `1 &lt; 2 &amp; 3`},
+		{"Attribute values are escaped", `<a title='"quoted"'>x</a>`, `<a title="&#34;quoted&#34;">x</a>`},
+		{"Comments removed", `a<!-- comment -->b`, `ab`},
+		{"Self closing tag", `a<br/>b`, `a<br>b`},
+		{"Nested dropped content", `<object><object>x</object>y</object>z`, `z`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			got := string(s.Sanitize(tc.Input))
+			if got != tc.Expected {
+				t.Errorf("Expected %q, but got %q", tc.Expected, got)
+			}
+		})
+	}
+}
+
+func TestSanitizer_CustomPolicy(t *testing.T) {
+	s := funcs.NewSanitizer(funcs.SanitizePolicy{
+		Tags: map[string][]string{
+			"a":    {"href"},
+			"span": {"class"},
+		},
+		GlobalAttributes: []string{"lang"},
+		URLSchemes:       []string{"https"},
+	})
+
+	testCases := []struct {
+		Name     string
+		Input    string
+		Expected string
+	}{
+		{"Per tag attribute", `<span class="c" id="i">x</span>`, `<span class="c">x</span>`},
+		{"Global attribute", `<a lang="en" class="c">x</a>`, `<a lang="en">x</a>`},
+		{"Allowed scheme without rel", `<a href="https://example.com" rel="me">x</a>`, `<a href="https://example.com">x</a>`},
+		{"Disallowed scheme", `<a href="http://example.com">x</a>`, `<a>x</a>`},
+		{"Tag not in policy", `<p>x</p>`, `x`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			got := string(s.Sanitize(tc.Input))
+			if got != tc.Expected {
+				t.Errorf("Expected %q, but got %q", tc.Expected, got)
+			}
+		})
+	}
+}
+
+func TestMarkdownRenderer_Sanitize(t *testing.T) {
+	md := funcs.NewMarkdownRenderer(funcs.MarkdownSanitize(funcs.NewSanitizer(funcs.UGCPolicy())))
+
+	out, err := md.Render("Hand made <b>mug</b>.<script>alert(1)</script>\n\n[shop](https://example.com)")
+	if err != nil {
+		t.Fatalf("Render failed: %s", err)
+	}
+
+	expected := "<p>Hand made <b>mug</b>.</p>\n<p><a href=\"https://example.com\" rel=\"nofollow ugc\">shop</a></p>\n"
+	if string(out) != expected {
+		t.Errorf("Expected %q, but got %q", expected, out)
+	}
+}
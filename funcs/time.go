@@ -2,11 +2,19 @@ package funcs
 
 import "time"
 
-func dateF(date interface{}, fmt string) string {
-	return dateFzone(date, fmt, "Local")
+// DateFormat formats date using the Go reference time layout, in the local
+// time zone. See DateFormatIn for the accepted date types.
+func DateFormat(date interface{}, layout string) string {
+	return DateFormatIn(date, layout, "Local")
 }
 
-func dateFzone(date interface{}, fmt string, zone string) string {
+// DateFormatIn formats date using the Go reference time layout, in the named
+// IANA time zone (e.g. "America/Los_Angeles"). An unknown zone falls back to
+// UTC.
+//
+// date may be a time.Time, a *time.Time, or Unix seconds as an int, int32 or
+// int64. Any other value, and a nil *time.Time, formats the current time.
+func DateFormatIn(date interface{}, layout string, zone string) string {
 	var t time.Time
 	switch date := date.(type) {
 	default:
@@ -14,7 +22,11 @@ func dateFzone(date interface{}, fmt string, zone string) string {
 	case time.Time:
 		t = date
 	case *time.Time:
-		t = *date
+		if date == nil {
+			t = time.Now()
+		} else {
+			t = *date
+		}
 	case int64:
 		t = time.Unix(date, 0)
 	case int:
@@ -25,8 +37,8 @@ func dateFzone(date interface{}, fmt string, zone string) string {
 
 	loc, err := time.LoadLocation(zone)
 	if err != nil {
-		loc, _ = time.LoadLocation("UTC")
+		loc = time.UTC
 	}
 
-	return t.In(loc).Format(fmt)
+	return t.In(loc).Format(layout)
 }
@@ -0,0 +1,77 @@
+package funcs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ukiahsmith/lemur/funcs"
+)
+
+func TestDateFormatIn(t *testing.T) {
+	// 2021-03-04 05:06:07 UTC
+	const unix = 1614834367
+	date := time.Unix(unix, 0).UTC()
+
+	testCases := []struct {
+		Name     string
+		Date     interface{}
+		Layout   string
+		Zone     string
+		Expected string
+	}{
+		{"time.Time", date, time.RFC3339, "UTC", "2021-03-04T05:06:07Z"},
+		{"*time.Time", &date, time.RFC3339, "UTC", "2021-03-04T05:06:07Z"},
+		{"int64", int64(unix), time.RFC3339, "UTC", "2021-03-04T05:06:07Z"},
+		{"int", int(unix), time.RFC3339, "UTC", "2021-03-04T05:06:07Z"},
+		{"int32", int32(unix), time.RFC3339, "UTC", "2021-03-04T05:06:07Z"},
+		{"Named zone", date, time.RFC3339, "Asia/Tokyo", "2021-03-04T14:06:07+09:00"},
+		{"Unknown zone falls back to UTC", date, time.RFC3339, "Not/A_Zone", "2021-03-04T05:06:07Z"},
+		{"Custom layout", date, "Jan 2, 2006", "UTC", "Mar 4, 2021"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			got := funcs.DateFormatIn(tc.Date, tc.Layout, tc.Zone)
+			if got != tc.Expected {
+				t.Errorf("Expected %q, but got %q", tc.Expected, got)
+			}
+		})
+	}
+}
+
+func TestDateFormatIn_UnsupportedTypeIsNow(t *testing.T) {
+	testCases := []struct {
+		Name string
+		Date interface{}
+	}{
+		{"nil", nil},
+		{"nil *time.Time", (*time.Time)(nil)},
+		{"string", "2021-03-04"},
+		{"float64", float64(1614834367)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			before := time.Now().Add(-time.Second)
+			got := funcs.DateFormatIn(tc.Date, time.RFC3339, "UTC")
+			after := time.Now().Add(time.Second)
+
+			parsed, err := time.Parse(time.RFC3339, got)
+			if err != nil {
+				t.Fatalf("Expected an RFC3339 time, but got %q: %s", got, err)
+			}
+			if parsed.Before(before) || parsed.After(after) {
+				t.Errorf("Expected the current time, but got %s", parsed)
+			}
+		})
+	}
+}
+
+func TestDateFormat(t *testing.T) {
+	date := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	expected := date.In(time.Local).Format(time.RFC1123Z)
+	if got := funcs.DateFormat(date, time.RFC1123Z); got != expected {
+		t.Errorf("Expected %q, but got %q", expected, got)
+	}
+}
@@ -1,12 +1,48 @@
 package funcs
 
 import (
+	"fmt"
 	"net/url"
 	"path"
 )
 
+// AbsURL returns a copy of baseURL with p joined onto its path.
 func AbsURL(baseURL *url.URL, p string) *url.URL {
 	u := *baseURL
 	u.Path = path.Join(u.Path, p)
 	return &u
 }
+
+// SiteAbsURL returns an "absURL" template func bound to a default base URL. It
+// accepts either just a path, `{{ absURL "cart" }}`, which is joined onto
+// baseURL, or an explicit base and path, `{{ absURL .Site.BaseURL "cart" }}`,
+// which behaves like AbsURL.
+func SiteAbsURL(baseURL *url.URL) func(args ...interface{}) (*url.URL, error) {
+	return func(args ...interface{}) (*url.URL, error) {
+		switch len(args) {
+		case 1:
+			p, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("absURL: path must be a string, got %T", args[0])
+			}
+			if baseURL == nil {
+				return nil, fmt.Errorf("absURL: no site base URL configured")
+			}
+			return AbsURL(baseURL, p), nil
+
+		case 2:
+			base, ok := args[0].(*url.URL)
+			if !ok || base == nil {
+				return nil, fmt.Errorf("absURL: base must be a *url.URL, got %T", args[0])
+			}
+			p, ok := args[1].(string)
+			if !ok {
+				return nil, fmt.Errorf("absURL: path must be a string, got %T", args[1])
+			}
+			return AbsURL(base, p), nil
+
+		default:
+			return nil, fmt.Errorf("absURL: want 1 or 2 arguments, got %d", len(args))
+		}
+	}
+}
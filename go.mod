@@ -3,3 +3,10 @@ module github.com/ukiahsmith/lemur
 go 1.17
 
 require github.com/yuin/goldmark v1.7.8
+
+require (
+	github.com/BurntSushi/toml v1.3.2
+	golang.org/x/image v0.10.0
+	golang.org/x/net v0.17.0
+	gopkg.in/yaml.v3 v3.0.1
+)
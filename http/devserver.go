@@ -0,0 +1,426 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ukiahsmith/lemur"
+	"github.com/ukiahsmith/lemur/scss"
+)
+
+// LiveReloadPath is the Server-Sent Events endpoint of a DevServer. A
+// "reload" event is sent to every connected browser after each rebuild.
+const LiveReloadPath = "/_lemur/livereload"
+
+// liveReloadScript is injected into every HTML response of a DevServer.
+const liveReloadScript = `<script>(function(){var es=new EventSource("` + LiveReloadPath + `");es.addEventListener("reload",function(){location.reload()});})();</script>`
+
+// DevServer serves a site built from a theme and a content tree for local
+// development. It rebuilds the whole site, see lemur.Build, whenever Watch
+// sees a file change, and tells connected browsers to reload. The Lemur is
+// created once in development mode, see lemur.WithDevMode, so a rebuild only
+// reloads the layouts that changed.
+//
+// When the theme fails to load or a page fails to render, every HTML page is
+// replaced with an overlay showing the error with its file and line, until a
+// rebuild succeeds.
+type DevServer struct {
+	theme fs.FS
+	src   fs.FS
+	opts  []lemur.Option
+
+	buildMu sync.Mutex
+	wh      *lemur.Lemur // nil until the theme first loads
+
+	mu    sync.RWMutex
+	dir   *buildDir // output of the last successful build
+	err   error     // error of the last build
+	stamp uint64    // fingerprint of the files the last build started from
+
+	// removing counts the replaced build directories waiting for their
+	// requests to finish before they are removed.
+	removing sync.WaitGroup
+
+	clientsMu sync.Mutex
+	clients   map[chan struct{}]struct{}
+}
+
+// buildDir is the output directory of a build. It is removed once a newer
+// build replaces it and the requests reading from it are done.
+type buildDir struct {
+	path     string
+	requests sync.WaitGroup
+}
+
+// devReloadInterval is the polling interval of the DevServer's Lemur. The
+// templates are reloaded before every build, rendering need not poll.
+const devReloadInterval = time.Hour
+
+// NewDevServer returns a DevServer building src with the theme, creating the
+// Lemur with opts. Call Rebuild or Watch before serving.
+func NewDevServer(theme fs.FS, src fs.FS, opts ...lemur.Option) *DevServer {
+	return &DevServer{
+		theme:   theme,
+		src:     src,
+		opts:    append(opts[:len(opts):len(opts)], lemur.WithDevMode(devReloadInterval)),
+		clients: make(map[chan struct{}]struct{}),
+	}
+}
+
+// Rebuild reloads the changed templates of the theme and builds the site into
+// a new temporary directory, then notifies connected browsers. The returned
+// error, also shown in the browser, is from lemur.New, Reload or Build. A
+// failed build keeps serving the last good one for files other than HTML
+// pages.
+func (s *DevServer) Rebuild(ctx context.Context) error {
+	stamp := s.fingerprint()
+	err := s.build(ctx)
+
+	s.mu.Lock()
+	s.err = err
+	s.stamp = stamp
+	s.mu.Unlock()
+
+	s.notify()
+
+	return err
+}
+
+func (s *DevServer) build(ctx context.Context) error {
+	s.buildMu.Lock()
+	defer s.buildMu.Unlock()
+
+	if s.wh == nil {
+		l, err := lemur.New(s.theme, nil, s.opts...)
+		if err != nil {
+			return err
+		}
+		s.wh = &l
+	} else if err := s.wh.Reload(); err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "lemur-serve-")
+	if err != nil {
+		return fmt.Errorf("lemur serve: %w", err)
+	}
+
+	if err := s.wh.Build(ctx, s.src, dir); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+
+	s.mu.Lock()
+	old := s.dir
+	s.dir = &buildDir{path: dir}
+	s.mu.Unlock()
+
+	s.remove(old)
+
+	return nil
+}
+
+// remove removes the replaced build directory d once its requests are done.
+// No request starts reading from d after it is replaced.
+func (s *DevServer) remove(d *buildDir) {
+	if d == nil {
+		return
+	}
+
+	s.removing.Add(1)
+	go func() {
+		defer s.removing.Done()
+		d.requests.Wait()
+		os.RemoveAll(d.path)
+	}()
+}
+
+// Watch rebuilds the site whenever a file of the theme or the content tree
+// differs from the last Rebuild, checking every interval until ctx is done.
+// Build errors are shown in the browser, and passed to onErr if it is not nil.
+func (s *DevServer) Watch(ctx context.Context, interval time.Duration, onErr func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.RLock()
+		last := s.stamp
+		s.mu.RUnlock()
+
+		if s.fingerprint() == last {
+			continue
+		}
+
+		if err := s.Rebuild(ctx); err != nil && onErr != nil {
+			onErr(err)
+		}
+	}
+}
+
+// fingerprint hashes the names, sizes and modification times of every file
+// of the theme and the content tree.
+func (s *DevServer) fingerprint() uint64 {
+	h := fnv.New64a()
+
+	for _, fsys := range []fs.FS{s.theme, s.src} {
+		// A walk error is hashed too, so that a directory appearing or
+		// disappearing is seen as a change.
+		err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\x00%d\x00%d\x00", p, info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(h, "%s\x00", err)
+		}
+	}
+
+	return h.Sum64()
+}
+
+// Close removes the output directory of the last build, and waits for the
+// replaced ones to be removed.
+func (s *DevServer) Close() error {
+	s.mu.Lock()
+	d := s.dir
+	s.dir = nil
+	s.mu.Unlock()
+
+	var err error
+	if d != nil {
+		d.requests.Wait()
+		err = os.RemoveAll(d.path)
+	}
+	s.removing.Wait()
+
+	return err
+}
+
+// ServeHTTP serves the live reload endpoint, the error overlay, or a file of
+// the last build, injecting the live reload script into HTML pages.
+func (s *DevServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == LiveReloadPath {
+		s.serveLiveReload(w, r)
+		return
+	}
+
+	s.mu.RLock()
+	dir, buildErr := s.dir, s.err
+	if dir != nil {
+		dir.requests.Add(1)
+	}
+	s.mu.RUnlock()
+
+	if dir != nil {
+		defer dir.requests.Done()
+	}
+
+	name := path.Clean("/" + r.URL.Path)
+	if strings.HasSuffix(r.URL.Path, "/") {
+		name = path.Join(name, "index.html")
+	}
+	isPage := path.Ext(name) == ".html"
+
+	if buildErr != nil && isPage {
+		s.serveOverlay(w, buildErr)
+		return
+	}
+	if dir == nil {
+		http.Error(w, "lemur serve: the site has not been built", http.StatusServiceUnavailable)
+		return
+	}
+
+	file := filepath.Join(dir.path, filepath.FromSlash(name))
+	if !isPage {
+		http.ServeFile(w, r, file)
+		return
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", DefaultContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(injectLiveReload(b))
+}
+
+// injectLiveReload inserts the live reload script before the closing body tag
+// of page, or appends it when there is none.
+func injectLiveReload(page []byte) []byte {
+	i := bytes.LastIndex(bytes.ToLower(page), []byte("</body>"))
+	if i < 0 {
+		return append(page, liveReloadScript...)
+	}
+
+	out := make([]byte, 0, len(page)+len(liveReloadScript))
+	out = append(out, page[:i]...)
+	out = append(out, liveReloadScript...)
+	return append(out, page[i:]...)
+}
+
+// serveLiveReload streams a "reload" event after every rebuild until the
+// client disconnects.
+func (s *DevServer) serveLiveReload(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "lemur serve: streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan struct{}, 1)
+	s.clientsMu.Lock()
+	s.clients[ch] = struct{}{}
+	s.clientsMu.Unlock()
+
+	defer func() {
+		s.clientsMu.Lock()
+		delete(s.clients, ch)
+		s.clientsMu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	fmt.Fprint(w, "retry: 1000\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			if _, err := fmt.Fprint(w, "event: reload\ndata: reload\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// notify sends a reload event to every connected browser. A browser that has
+// not yet received the previous event only gets one.
+func (s *DevServer) notify() {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	for ch := range s.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// overlayError is a single error shown by the overlay.
+type overlayError struct {
+	File    string
+	Layout  string
+	Line    int
+	Col     int
+	Message string
+}
+
+// Location returns the file, line and column as "file:line:col", leaving out
+// the parts that are unknown.
+func (e overlayError) Location() string {
+	loc := e.File
+	if e.Line > 0 {
+		loc += fmt.Sprintf(":%d", e.Line)
+		if e.Col > 0 {
+			loc += fmt.Sprintf(":%d", e.Col)
+		}
+	}
+
+	return loc
+}
+
+var overlayTemplate = template.Must(template.New("overlay").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>lemur: build failed</title></head>
+<body style="margin:0;font-family:ui-monospace,monospace;background:#1e1e1e;color:#eee">
+<div style="padding:2em">
+<h1 style="color:#ff6b6b;font-size:1.4em">Build failed</h1>
+{{ range . }}<div style="margin:1em 0;padding:1em;background:#2d2d2d;border-left:4px solid #ff6b6b">
+{{ with .Location }}<div style="color:#8cc8ff">{{ . }}</div>{{ end }}{{ with .Layout }}<div style="color:#999">in layout {{ . }}</div>{{ end }}
+<pre style="white-space:pre-wrap;margin:.5em 0 0">{{ .Message }}</pre>
+</div>
+{{ end }}</div>
+</body>
+</html>
+`))
+
+// serveOverlay renders err as an error page with the live reload script, so
+// the page recovers by itself once the error is fixed.
+func (s *DevServer) serveOverlay(w http.ResponseWriter, err error) {
+	var buf bytes.Buffer
+	if execErr := overlayTemplate.Execute(&buf, overlayErrors(err)); execErr != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", DefaultContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = w.Write(injectLiveReload(buf.Bytes()))
+}
+
+// overlayErrors splits err into the errors to show, locating each in its
+// file where lemur reported a position.
+func overlayErrors(err error) []overlayError {
+	var loadErrs lemur.LoadErrors
+	if errors.As(err, &loadErrs) {
+		out := make([]overlayError, 0, len(loadErrs))
+		for _, le := range loadErrs {
+			out = append(out, overlayError{File: le.Path, Line: le.Line, Col: le.Col, Message: le.Err.Error()})
+		}
+		return out
+	}
+
+	var renderErr *lemur.RenderError
+	if errors.As(err, &renderErr) && renderErr.Template != "" {
+		return []overlayError{{
+			File:    renderErr.Template,
+			Layout:  renderErr.Resolved,
+			Line:    renderErr.Line,
+			Col:     renderErr.Col,
+			Message: err.Error(),
+		}}
+	}
+
+	var scssErr *scss.Error
+	if errors.As(err, &scssErr) {
+		return []overlayError{{File: scssErr.File, Line: scssErr.Line, Message: scssErr.Err.Error()}}
+	}
+
+	return []overlayError{{Message: err.Error()}}
+}
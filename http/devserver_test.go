@@ -0,0 +1,268 @@
+package http_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	lemurhttp "github.com/ukiahsmith/lemur/http"
+)
+
+func devServerTestFS() (fstest.MapFS, fstest.MapFS) {
+	theme := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`<html><body>{{ .Page.Content }}</body></html>`)},
+		"static/css/style.css":               &fstest.MapFile{Data: []byte(`body {}`)},
+	}
+	src := fstest.MapFS{
+		"content/index.md": &fstest.MapFile{Data: []byte("# Home")},
+		"content/about.md": &fstest.MapFile{Data: []byte("About")},
+	}
+
+	return theme, src
+}
+
+func TestDevServer(t *testing.T) {
+	theme, src := devServerTestFS()
+
+	dev := lemurhttp.NewDevServer(theme, src)
+	defer dev.Close()
+
+	if err := dev.Rebuild(context.Background()); err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+
+	testCases := []struct {
+		Name        string
+		Path        string
+		Status      int
+		Contains    string
+		LiveReload  bool
+		ContentType string
+	}{
+		{"home", "/", http.StatusOK, `<h1 id="home">Home</h1>`, true, lemurhttp.DefaultContentType},
+		{"pretty URL", "/about/", http.StatusOK, "<p>About</p>", true, lemurhttp.DefaultContentType},
+		{"directory redirect", "/about", http.StatusMovedPermanently, "", false, ""},
+		{"static file", "/css/style.css", http.StatusOK, "body {}", false, "text/css; charset=utf-8"},
+		{"missing page", "/missing/", http.StatusNotFound, "", false, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			dev.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.Path, nil))
+
+			if rec.Code != tc.Status {
+				t.Fatalf("Expected status %d, but got %d", tc.Status, rec.Code)
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, tc.Contains) {
+				t.Errorf("Expected body to contain %q, but got %q", tc.Contains, body)
+			}
+			if got := strings.Contains(body, lemurhttp.LiveReloadPath+`");`) && strings.HasSuffix(body, "</script></body></html>"); got != tc.LiveReload {
+				t.Errorf("Expected live reload script injected before </body> to be %t, but got %q", tc.LiveReload, body)
+			}
+			if tc.ContentType != "" && rec.Header().Get("Content-Type") != tc.ContentType {
+				t.Errorf("Expected Content-Type %q, but got %q", tc.ContentType, rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestDevServer_ErrorOverlay(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Template string
+		Contains string
+	}{
+		{"load error", "<html><body>\n{{ .Page.Content ", "layouts/_defaults/_index.html.tmpl:2"},
+		{"render error", "<html><body>\n{{ .Page.Missing }}</body></html>", "_index.html.tmpl:2:8"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			theme, src := devServerTestFS()
+			theme["layouts/_defaults/_index.html.tmpl"] = &fstest.MapFile{Data: []byte(tc.Template)}
+
+			dev := lemurhttp.NewDevServer(theme, src)
+			defer dev.Close()
+
+			if err := dev.Rebuild(context.Background()); err == nil {
+				t.Fatalf("Expected Rebuild to fail, but got nil")
+			}
+
+			rec := httptest.NewRecorder()
+			dev.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("Expected status %d, but got %d", http.StatusInternalServerError, rec.Code)
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, tc.Contains) {
+				t.Errorf("Expected overlay to contain %q, but got %q", tc.Contains, body)
+			}
+			if !strings.Contains(body, lemurhttp.LiveReloadPath) {
+				t.Errorf("Expected overlay to include the live reload script, but got %q", body)
+			}
+		})
+	}
+}
+
+// blockingWriter blocks the first Write of a response until release is
+// closed, to hold a request in flight.
+type blockingWriter struct {
+	*httptest.ResponseRecorder
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(b []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.release
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestDevServer_Rebuild(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	buildDirs := func() int {
+		t.Helper()
+		dirs, err := filepath.Glob(filepath.Join(tmp, "lemur-serve-*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(dirs)
+	}
+
+	theme, src := devServerTestFS()
+	dev := lemurhttp.NewDevServer(theme, src)
+	defer dev.Close()
+
+	if err := dev.Rebuild(context.Background()); err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+
+	// A changed layout is reloaded by the next rebuild.
+	theme["layouts/_defaults/_index.html.tmpl"] = &fstest.MapFile{Data: []byte(`<html><body>v2 {{ .Page.Content }}</body></html>`), ModTime: time.Unix(1, 0)}
+
+	w := &blockingWriter{ResponseRecorder: httptest.NewRecorder(), started: make(chan struct{}), release: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		dev.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/css/style.css", nil))
+	}()
+	<-w.started
+
+	if err := dev.Rebuild(context.Background()); err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	dev.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(rec.Body.String(), "v2 <h1") {
+		t.Errorf("Expected the reloaded layout, but got %q", rec.Body.String())
+	}
+
+	// The replaced build is kept while a request reads from it.
+	if n := buildDirs(); n != 2 {
+		t.Errorf("Expected 2 build directories while a request is in flight, but got %d", n)
+	}
+	close(w.release)
+	<-done
+	if w.Body.String() != "body {}" {
+		t.Errorf("Expected the in-flight request to complete, but got %q", w.Body.String())
+	}
+
+	if err := dev.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if n := buildDirs(); n != 0 {
+		t.Errorf("Expected Close to remove every build directory, but got %d", n)
+	}
+}
+
+func TestDevServer_LiveReload(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string, mtime time.Time) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("layouts/_defaults/_index.html.tmpl", `{{ .Page.Content }}`, time.Now().Add(-time.Hour))
+	write("content/index.html", "v1", time.Now().Add(-time.Hour))
+
+	dev := lemurhttp.NewDevServer(os.DirFS(dir), os.DirFS(dir))
+	defer dev.Close()
+	if err := dev.Rebuild(context.Background()); err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+
+	srv := httptest.NewServer(dev)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dev.Watch(ctx, 10*time.Millisecond, nil)
+
+	resp, err := http.Get(srv.URL + lemurhttp.LiveReloadPath)
+	if err != nil {
+		t.Fatalf("Failed to connect to live reload: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected Content-Type text/event-stream, but got %q", ct)
+	}
+
+	write("content/index.html", "v2", time.Now())
+
+	events := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "event: ") {
+				events <- strings.TrimPrefix(scanner.Text(), "event: ")
+				return
+			}
+		}
+	}()
+
+	select {
+	case event := <-events:
+		if event != "reload" {
+			t.Errorf("Expected a reload event, but got %q", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for a reload event")
+	}
+
+	page, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatalf("Failed to get page: %v", err)
+	}
+	defer page.Body.Close()
+
+	var body strings.Builder
+	if _, err := bufio.NewReader(page.Body).WriteTo(&body); err != nil {
+		t.Fatalf("Failed to read page: %v", err)
+	}
+	if !strings.HasPrefix(body.String(), "v2") {
+		t.Errorf("Expected the rebuilt page, but got %q", body.String())
+	}
+}
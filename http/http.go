@@ -0,0 +1,226 @@
+// Package http renders lemur layouts from net/http handlers.
+//
+// A Renderer wraps a *lemur.Lemur and builds handlers that call a data
+// function, render a layout into a buffer, set the response headers and map
+// failures to status codes, rendering an optional error layout from the same
+// Lemur.
+//
+// A DevServer serves a whole site built with lemur.Build for local
+// development, with live reload and an in-browser error overlay.
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ukiahsmith/lemur"
+)
+
+// DefaultContentType is the Content-Type of rendered responses unless
+// changed with WithContentType.
+const DefaultContentType = "text/html; charset=utf-8"
+
+// DataFunc produces the data a layout is rendered with for a request. A
+// returned error is rendered as an error page, see StatusCode.
+type DataFunc func(r *http.Request) (interface{}, error)
+
+// StatusError is an error with the HTTP status code it should be reported
+// with, e.g. returned from a DataFunc when a requested record does not exist.
+type StatusError struct {
+	Code int
+	Err  error
+}
+
+// Error returns a StatusError reporting err with the status code.
+func Error(code int, err error) error {
+	return &StatusError{Code: code, Err: err}
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Code, http.StatusText(e.Code), e.Err)
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns the HTTP status code err should be reported with:
+// the code of a StatusError, 404 for lemur.ErrUnknownLayout and
+// lemur.ErrUnknownTemplate, and 500 for anything else, including
+// lemur.ErrExec.
+func StatusCode(err error) int {
+	var statusErr *StatusError
+	switch {
+	case errors.As(err, &statusErr):
+		return statusErr.Code
+	case errors.Is(err, lemur.ErrUnknownLayout), errors.Is(err, lemur.ErrUnknownTemplate):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorData is the data an error layout is rendered with. Site and Page are
+// there for the layouts an error layout shares with pages: Site is the Lemur's,
+// see lemur.WithSite, and Page is titled with the status text.
+type ErrorData struct {
+	Status     int
+	StatusText string
+	Err        error
+	Request    *http.Request
+
+	Site lemur.Site
+	Page lemur.Page
+}
+
+// Renderer builds http.Handlers that render layouts from a Lemur.
+type Renderer struct {
+	lemur       *lemur.Lemur
+	errorLayout string
+	contentType string
+}
+
+// Option configures a Renderer.
+type Option func(*Renderer)
+
+// WithErrorLayout renders failures with the named layout, passing an
+// ErrorData. Without it, or if the error layout itself fails, a plain text
+// status message is sent.
+func WithErrorLayout(layout string) Option {
+	return func(rr *Renderer) {
+		rr.errorLayout = layout
+	}
+}
+
+// WithContentType sets the Content-Type of rendered responses.
+func WithContentType(contentType string) Option {
+	return func(rr *Renderer) {
+		rr.contentType = contentType
+	}
+}
+
+// New returns a Renderer for l.
+func New(l *lemur.Lemur, opts ...Option) *Renderer {
+	rr := &Renderer{
+		lemur:       l,
+		contentType: DefaultContentType,
+	}
+	for _, opt := range opts {
+		opt(rr)
+	}
+
+	return rr
+}
+
+// Handler returns an http.Handler that renders layout with the data returned
+// by data, which may be nil to render with no data. The layout is rendered
+// into a buffer first, so a failing template never sends a partial page.
+func (rr *Renderer) Handler(layout string, data DataFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var d interface{}
+		if data != nil {
+			var err error
+			d, err = data(r)
+			if err != nil {
+				rr.Error(w, r, err)
+				return
+			}
+		}
+
+		rr.Render(w, r, http.StatusOK, layout, d)
+	})
+}
+
+// Render renders layout with data and sends it with the status code. If
+// rendering fails the error page is sent instead. Rendering stops when the
+// request's context is done.
+func (rr *Renderer) Render(w http.ResponseWriter, r *http.Request, status int, layout string, data interface{}) {
+	buf := lemur.GetBuffer()
+	defer lemur.PutBuffer(buf)
+
+	if err := rr.lemur.RenderContext(r.Context(), buf, layout, data); err != nil {
+		rr.Error(w, r, err)
+		return
+	}
+
+	rr.write(w, status, rr.contentType, buf)
+}
+
+// Error sends the error page for err, with the status code from StatusCode.
+func (rr *Renderer) Error(w http.ResponseWriter, r *http.Request, err error) {
+	status := StatusCode(err)
+
+	if rr.errorLayout != "" {
+		buf := lemur.GetBuffer()
+		defer lemur.PutBuffer(buf)
+
+		data := ErrorData{
+			Status:     status,
+			StatusText: http.StatusText(status),
+			Err:        err,
+			Request:    r,
+			Site:       rr.lemur.Site(),
+			Page:       lemur.Page{Title: http.StatusText(status)},
+		}
+		if rr.lemur.RenderContext(r.Context(), buf, rr.errorLayout, data) == nil {
+			rr.write(w, status, rr.contentType, buf)
+			return
+		}
+	}
+
+	http.Error(w, http.StatusText(status), status)
+}
+
+// Recover is middleware that recovers a panic in next and sends a 500 error
+// page for it. The panic is only recovered if next has not yet written the
+// response headers, otherwise it is re-raised.
+func (rr *Renderer) Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &trackingWriter{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler || rw.wroteHeader {
+				panic(v)
+			}
+			rr.Error(w, r, fmt.Errorf("panic: %v", v))
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// Handler returns an http.Handler that renders layout from l with the data
+// returned by data, as Renderer.Handler does.
+func Handler(l *lemur.Lemur, layout string, data DataFunc, opts ...Option) http.Handler {
+	return New(l, opts...).Handler(layout, data)
+}
+
+// write sends buf as the complete response.
+func (rr *Renderer) write(w http.ResponseWriter, status int, contentType string, buf *bytes.Buffer) {
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+}
+
+// trackingWriter records whether the response headers have been written.
+type trackingWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (tw *trackingWriter) WriteHeader(status int) {
+	tw.wroteHeader = true
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *trackingWriter) Write(p []byte) (int, error) {
+	tw.wroteHeader = true
+	return tw.ResponseWriter.Write(p)
+}
@@ -0,0 +1,195 @@
+package http_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ukiahsmith/lemur"
+	lemurhttp "github.com/ukiahsmith/lemur/http"
+)
+
+func newLemur(t *testing.T) *lemur.Lemur {
+	t.Helper()
+
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`<p>{{ block "main.html.tmpl" . }}{{ end }}</p>`)},
+		"layouts/product/main.html.tmpl":     &fstest.MapFile{Data: []byte(`{{ .Name }} costs {{ mod .Price .Divisor }}`)},
+		"layouts/error/main.html.tmpl":       &fstest.MapFile{Data: []byte(`error {{ .Status }} {{ .StatusText }}`)},
+	}
+
+	l, err := lemur.New(templateFS, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	return &l
+}
+
+type product struct {
+	Name    string
+	Price   int
+	Divisor int
+}
+
+func TestHandler(t *testing.T) {
+	l := newLemur(t)
+
+	testCases := []struct {
+		Name         string
+		Layout       string
+		Data         lemurhttp.DataFunc
+		Options      []lemurhttp.Option
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{
+			Name:   "Renders layout",
+			Layout: "product",
+			Data: func(r *http.Request) (interface{}, error) {
+				return product{Name: r.URL.Query().Get("name"), Price: 7, Divisor: 4}, nil
+			},
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: "<p>mug costs 3</p>",
+		},
+		{
+			Name:         "Unknown layout is not found",
+			Layout:       "nonexistent",
+			ExpectedCode: http.StatusNotFound,
+			ExpectedBody: "Not Found\n",
+		},
+		{
+			Name:   "Exec error is internal server error",
+			Layout: "product",
+			Data: func(r *http.Request) (interface{}, error) {
+				return product{Name: "mug", Price: 7, Divisor: 0}, nil
+			},
+			ExpectedCode: http.StatusInternalServerError,
+			ExpectedBody: "Internal Server Error\n",
+		},
+		{
+			Name:   "Data func status error",
+			Layout: "product",
+			Data: func(r *http.Request) (interface{}, error) {
+				return nil, lemurhttp.Error(http.StatusNotFound, errors.New("no such product"))
+			},
+			Options:      []lemurhttp.Option{lemurhttp.WithErrorLayout("error")},
+			ExpectedCode: http.StatusNotFound,
+			ExpectedBody: "<p>error 404 Not Found</p>",
+		},
+		{
+			Name:         "Error layout",
+			Layout:       "nonexistent",
+			Options:      []lemurhttp.Option{lemurhttp.WithErrorLayout("error")},
+			ExpectedCode: http.StatusNotFound,
+			ExpectedBody: "<p>error 404 Not Found</p>",
+		},
+		{
+			Name:         "Broken error layout falls back to plain text",
+			Layout:       "nonexistent",
+			Options:      []lemurhttp.Option{lemurhttp.WithErrorLayout("also-nonexistent")},
+			ExpectedCode: http.StatusNotFound,
+			ExpectedBody: "Not Found\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			h := lemurhttp.Handler(l, tc.Layout, tc.Data, tc.Options...)
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?name=mug", nil))
+
+			if rec.Code != tc.ExpectedCode {
+				t.Errorf("Expected status %d, but got %d", tc.ExpectedCode, rec.Code)
+			}
+			if rec.Body.String() != tc.ExpectedBody {
+				t.Errorf("Expected body %q, but got %q", tc.ExpectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_Headers(t *testing.T) {
+	l := newLemur(t)
+
+	h := lemurhttp.Handler(l, "", nil, lemurhttp.WithContentType("text/plain; charset=utf-8"))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := rec.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Expected Content-Type %q, but got %q", "text/plain; charset=utf-8", got)
+	}
+	if got := rec.Header().Get("Content-Length"); got != "7" {
+		t.Errorf("Expected Content-Length %q, but got %q", "7", got)
+	}
+}
+
+func TestRenderer_Recover(t *testing.T) {
+	rr := lemurhttp.New(newLemur(t), lemurhttp.WithErrorLayout("error"))
+
+	h := rr.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, but got %d", http.StatusInternalServerError, rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "error 500") {
+		t.Errorf("Expected the error layout, but got %q", rec.Body.String())
+	}
+}
+
+func TestRenderer_ErrorWithSite(t *testing.T) {
+	// The error layout falls back to a _defaults that reads .Page and .Site,
+	// as the default theme's does.
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ with .Page.Title }}{{ . }} | {{ end }}{{ .Site.Title }}: {{ block "main.html.tmpl" . }}{{ end }}`)},
+		"layouts/error/main.html.tmpl":       &fstest.MapFile{Data: []byte(`error {{ .Status }}`)},
+	}
+
+	l, err := lemur.New(templateFS, nil, lemur.WithSite(lemur.Site{Title: "YoYo-Pens"}))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	rr := lemurhttp.New(&l, lemurhttp.WithErrorLayout("error"))
+	rec := httptest.NewRecorder()
+	rr.Error(rec, httptest.NewRequest(http.MethodGet, "/", nil), lemurhttp.Error(http.StatusNotFound, errors.New("no such pen")))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, but got %d", http.StatusNotFound, rec.Code)
+	}
+	if expected := "Not Found | YoYo-Pens: error 404"; rec.Body.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, rec.Body.String())
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Err      error
+		Expected int
+	}{
+		{"Unknown layout", &lemur.RenderError{Kind: lemur.ErrUnknownLayout}, http.StatusNotFound},
+		{"Unknown template", &lemur.RenderError{Kind: lemur.ErrUnknownTemplate}, http.StatusNotFound},
+		{"Exec", &lemur.RenderError{Kind: lemur.ErrExec}, http.StatusInternalServerError},
+		{"Status error", lemurhttp.Error(http.StatusForbidden, errors.New("no")), http.StatusForbidden},
+		{"Other", errors.New("other"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := lemurhttp.StatusCode(tc.Err); got != tc.Expected {
+				t.Errorf("Expected status %d, but got %d", tc.Expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package lemur
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+// Image is an image processed by the imageResize and imageFill template funcs.
+type Image struct {
+	// URL is the URL path the image is served at by StaticHandler, and
+	// written to by Build.
+	URL    string
+	Width  int
+	Height int
+}
+
+// imageFormats are the formats images can be written in, by the extension
+// they are written with.
+var imageFormats = map[string]string{
+	"png":  ".png",
+	"jpg":  ".jpg",
+	"jpeg": ".jpg",
+	"gif":  ".gif",
+}
+
+// WithImageCacheDir sets the directory the imageResize and imageFill funcs
+// write processed images to. Images are reused from it across runs for as long
+// as their source does not change. The default is a lemur/images directory
+// in the user's cache directory.
+func WithImageCacheDir(dir string) Option {
+	return func(wh *Lemur) {
+		wh.assets.imageDir = dir
+	}
+}
+
+// imageProcessor holds the processed images of an asset pipeline.
+type imageProcessor struct {
+	imageDir string
+
+	imagesMu        sync.Mutex
+	images          map[string]bool        // names of the images processed by this Lemur
+	publishedImages map[string]bool        // names of the images used since Build started
+	locks           map[string]*sync.Mutex // held while an image is processed
+}
+
+// imageResize scales the image name, an asset name such as "img/logo.png",
+// to width pixels wide, keeping its aspect ratio. An optional format of "png",
+// "jpg" or "gif" converts the image. It is the "imageResize" template func.
+func (p *assetPipeline) imageResize(name string, width int, format ...string) (Image, error) {
+	if width <= 0 {
+		return Image{}, fmt.Errorf("lemur imageResize: width must be positive, got %d", width)
+	}
+
+	return p.processImage("imageResize", name, "resize", width, 0, format)
+}
+
+// imageFill scales and crops the image name to exactly width by height pixels,
+// cutting off the edges of the longer side. An optional format converts the
+// image, as for imageResize. It is the "imageFill" template func.
+func (p *assetPipeline) imageFill(name string, width int, height int, format ...string) (Image, error) {
+	if width <= 0 || height <= 0 {
+		return Image{}, fmt.Errorf("lemur imageFill: width and height must be positive, got %dx%d", width, height)
+	}
+
+	return p.processImage("imageFill", name, "fill", width, height, format)
+}
+
+// processImage returns the processed image, from the image cache directory
+// when it was processed before.
+func (p *assetPipeline) processImage(fn string, name string, op string, width int, height int, format []string) (Image, error) {
+	e, err := p.asset(fn, name)
+	if err != nil {
+		return Image{}, err
+	}
+
+	ext := strings.ToLower(path.Ext(e.name))
+	switch {
+	case len(format) > 1:
+		return Image{}, fmt.Errorf("lemur %s: expected one format, got %d", fn, len(format))
+	case len(format) == 1:
+		var ok bool
+		if ext, ok = imageFormats[strings.ToLower(format[0])]; !ok {
+			return Image{}, fmt.Errorf("lemur %s: unsupported format %q, use png, jpg or gif", fn, format[0])
+		}
+	case ext == ".jpeg":
+		ext = ".jpg"
+	}
+
+	// The name of a processed image is unique to its source content and the
+	// processing, so that it can be cached for good.
+	key := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%d|%s", e.etag, op, width, height, ext)))
+	base := strings.TrimSuffix(e.name, path.Ext(e.name))
+	out := fmt.Sprintf("%s_%s_%s", base, op, hex.EncodeToString(key[:4]))
+
+	lock := p.imageLock(out)
+	lock.Lock()
+	defer lock.Unlock()
+
+	dir := p.imageCacheDir()
+
+	// A WebP source is written as JPEG or PNG, which is only known once it is
+	// decoded.
+	candidates := []string{ext}
+	if ext == ".webp" {
+		candidates = []string{".jpg", ".png"}
+	}
+	for _, cachedExt := range candidates {
+		cached := filepath.Join(dir, filepath.FromSlash(out+cachedExt))
+		if img, ok := cachedImage(cached, out+cachedExt); ok {
+			p.addImage(img.URL)
+			return img, nil
+		}
+	}
+
+	src, _, err := p.open(e)
+	if err != nil {
+		return Image{}, fmt.Errorf("lemur %s: %w", fn, err)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(src))
+	if err != nil {
+		return Image{}, fmt.Errorf("lemur %s: decoding %s: %w", fn, e.name, err)
+	}
+
+	var dst image.Image
+	if op == "fill" {
+		dst = fillImage(decoded, width, height)
+	} else {
+		dst = resizeImage(decoded, width)
+	}
+
+	if ext == ".webp" {
+		// There is no WebP encoder, write photos as JPEG and images with
+		// transparency as PNG.
+		ext = ".jpg"
+		if o, ok := decoded.(interface{ Opaque() bool }); ok && !o.Opaque() {
+			ext = ".png"
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, dst, ext); err != nil {
+		return Image{}, fmt.Errorf("lemur %s: encoding %s: %w", fn, out+ext, err)
+	}
+
+	file := filepath.Join(dir, filepath.FromSlash(out+ext))
+	if err := writeFileAtomic(file, buf.Bytes()); err != nil {
+		return Image{}, fmt.Errorf("lemur %s: %w", fn, err)
+	}
+
+	p.addImage("/" + out + ext)
+	bounds := dst.Bounds()
+
+	return Image{URL: "/" + out + ext, Width: bounds.Dx(), Height: bounds.Dy()}, nil
+}
+
+// cachedImage returns the processed image cached in file, if it is there.
+func cachedImage(file string, name string) (Image, bool) {
+	f, err := os.Open(file)
+	if err != nil {
+		return Image{}, false
+	}
+	defer f.Close()
+
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return Image{}, false
+	}
+
+	return Image{URL: "/" + name, Width: config.Width, Height: config.Height}, true
+}
+
+// resizeImage scales img to width, keeping its aspect ratio.
+func resizeImage(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	height := (b.Dy()*width + b.Dx()/2) / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+
+	return dst
+}
+
+// fillImage scales img to cover width by height and crops it to the center.
+func fillImage(img image.Image, width int, height int) image.Image {
+	b := img.Bounds()
+
+	// The largest centered part of img with the target aspect ratio.
+	crop := b
+	if b.Dx()*height > b.Dy()*width {
+		w := (b.Dy()*width + height/2) / height
+		crop.Min.X = b.Min.X + (b.Dx()-w)/2
+		crop.Max.X = crop.Min.X + w
+	} else {
+		h := (b.Dx()*height + width/2) / width
+		crop.Min.Y = b.Min.Y + (b.Dy()-h)/2
+		crop.Max.Y = crop.Min.Y + h
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+
+	return dst
+}
+
+// encodeImage writes img in the format of the extension ext. JPEG has no
+// transparency, so a JPEG is drawn on a white background.
+func encodeImage(buf *bytes.Buffer, img image.Image, ext string) error {
+	switch ext {
+	case ".png":
+		return png.Encode(buf, img)
+	case ".gif":
+		return gif.Encode(buf, img, nil)
+	case ".jpg":
+		flat := image.NewRGBA(img.Bounds())
+		draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+		return jpeg.Encode(buf, flat, &jpeg.Options{Quality: 85})
+	}
+
+	return fmt.Errorf("unsupported image format %q", ext)
+}
+
+// imageCacheDir returns the directory processed images are written to.
+func (p *assetPipeline) imageCacheDir() string {
+	if p.imageDir != "" {
+		return p.imageDir
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "lemur", "images")
+}
+
+func (p *assetPipeline) imageLock(name string) *sync.Mutex {
+	p.imagesMu.Lock()
+	defer p.imagesMu.Unlock()
+
+	if p.locks == nil {
+		p.locks = make(map[string]*sync.Mutex)
+	}
+	lock, ok := p.locks[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		p.locks[name] = lock
+	}
+
+	return lock
+}
+
+func (p *assetPipeline) addImage(url string) {
+	p.imagesMu.Lock()
+	defer p.imagesMu.Unlock()
+
+	if p.images == nil {
+		p.images = make(map[string]bool)
+	}
+	if p.publishedImages == nil {
+		p.publishedImages = make(map[string]bool)
+	}
+	name := strings.TrimPrefix(url, "/")
+	p.images[name] = true
+	p.publishedImages[name] = true
+}
+
+// processedImage returns the path in the image cache directory of the
+// processed image served at name, if this Lemur processed it.
+func (p *assetPipeline) processedImage(name string) (string, bool) {
+	p.imagesMu.Lock()
+	ok := p.images[name]
+	p.imagesMu.Unlock()
+	if !ok {
+		return "", false
+	}
+
+	return filepath.Join(p.imageCacheDir(), filepath.FromSlash(name)), true
+}
+
+// writeImages copies every image used since Build started below outDir. The
+// images of earlier renders stay served by StaticHandler, but are not written.
+func (p *assetPipeline) writeImages(outDir string) error {
+	if p == nil {
+		return nil
+	}
+
+	p.imagesMu.Lock()
+	names := make([]string, 0, len(p.publishedImages))
+	for name := range p.publishedImages {
+		names = append(names, name)
+	}
+	p.imagesMu.Unlock()
+
+	for _, name := range names {
+		file, _ := p.processedImage(name)
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("lemur Build: %w", err)
+		}
+		if err := writeFile(filepath.Join(outDir, filepath.FromSlash(name)), b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to name and renames it
+// into place, so that a reader never sees a partly written file.
+func writeFileAtomic(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), name); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package lemur_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ukiahsmith/lemur"
+)
+
+// testPNG returns a PNG of the given size, red on the left half and blue on
+// the right.
+func testPNG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= width/2 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestLemur_ImageFuncs(t *testing.T) {
+	themeFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`x`)},
+		"layouts/resize/_index.html.tmpl":    &fstest.MapFile{Data: []byte(`{{ with imageResize "img/photo.png" 40 }}{{ .URL }} {{ .Width }}x{{ .Height }}{{ end }}`)},
+		"layouts/fill/_index.html.tmpl":      &fstest.MapFile{Data: []byte(`{{ with imageFill "img/photo.png" 30 30 }}{{ .URL }} {{ .Width }}x{{ .Height }}{{ end }}`)},
+		"layouts/convert/_index.html.tmpl":   &fstest.MapFile{Data: []byte(`{{ with imageResize "img/photo.png" 20 "jpg" }}{{ .URL }} {{ .Width }}x{{ .Height }}{{ end }}`)},
+		"layouts/missing/_index.html.tmpl":   &fstest.MapFile{Data: []byte(`{{ imageResize "img/nope.png" 20 }}`)},
+		"layouts/format/_index.html.tmpl":    &fstest.MapFile{Data: []byte(`{{ imageResize "img/photo.png" 20 "bmp" }}`)},
+		"assets/img/photo.png":               &fstest.MapFile{Data: testPNG(t, 80, 40)},
+	}
+
+	cacheDir := t.TempDir()
+	wh, err := lemur.New(themeFS, nil, lemur.WithImageCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	testCases := []struct {
+		Name   string
+		Layout string
+		Size   string
+		Ext    string
+	}{
+		{"resize keeps the aspect ratio", "resize", "40x20", ".png"},
+		{"fill crops to the size", "fill", "30x30", ".png"},
+		{"format conversion", "convert", "20x10", ".jpg"},
+	}
+
+	urls := make(map[string]string)
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			out, err := wh.Srender(tc.Layout, nil)
+			if err != nil {
+				t.Fatalf("Srender failed: %v", err)
+			}
+
+			fields := strings.Fields(out)
+			if len(fields) != 2 {
+				t.Fatalf("Expected a URL and a size, but got %q", out)
+			}
+			url, size := fields[0], fields[1]
+			urls[tc.Layout] = url
+
+			if !strings.HasPrefix(url, "/img/photo_") || filepath.Ext(url) != tc.Ext {
+				t.Errorf("Expected a %s URL below /img/photo_, but got %q", tc.Ext, url)
+			}
+			if size != tc.Size {
+				t.Errorf("Expected size %s, but got %s", tc.Size, size)
+			}
+
+			f, err := os.Open(filepath.Join(cacheDir, filepath.FromSlash(url)))
+			if err != nil {
+				t.Fatalf("Expected the image in the cache directory: %v", err)
+			}
+			defer f.Close()
+			config, format, err := image.DecodeConfig(f)
+			if err != nil {
+				t.Fatalf("Failed to decode the cached image: %v", err)
+			}
+			if got := fmt.Sprintf("%dx%d", config.Width, config.Height); got != tc.Size {
+				t.Errorf("Expected a cached image of %s, but got %s", tc.Size, got)
+			}
+			if "."+format != tc.Ext && !(format == "jpeg" && tc.Ext == ".jpg") {
+				t.Errorf("Expected a %s image, but got %s", tc.Ext, format)
+			}
+		})
+	}
+
+	for _, layout := range []string{"missing", "format"} {
+		if _, err := wh.Srender(layout, nil); err == nil {
+			t.Errorf("Expected layout %s to fail, but got no error", layout)
+		}
+	}
+
+	// The fill crops the left and right edges, keeping the red and blue
+	// halves in the middle.
+	b, err := os.ReadFile(filepath.Join(cacheDir, filepath.FromSlash(urls["fill"])))
+	if err != nil {
+		t.Fatalf("Failed to read the filled image: %v", err)
+	}
+	filled, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Failed to decode the filled image: %v", err)
+	}
+	if r, _, bl, _ := filled.At(2, 15).RGBA(); r>>8 != 255 || bl != 0 {
+		t.Errorf("Expected the left of the filled image to be red, but got %v", filled.At(2, 15))
+	}
+	if r, _, bl, _ := filled.At(27, 15).RGBA(); r != 0 || bl>>8 != 255 {
+		t.Errorf("Expected the right of the filled image to be blue, but got %v", filled.At(27, 15))
+	}
+
+	// A new Lemur reuses the cached image rather than processing it again.
+	if err := os.WriteFile(filepath.Join(cacheDir, filepath.FromSlash(urls["convert"])), mustJPEG(t, 7, 3), 0o644); err != nil {
+		t.Fatalf("Failed to overwrite the cached image: %v", err)
+	}
+	wh2, err := lemur.New(themeFS, nil, lemur.WithImageCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("lemur.New failed: %v", err)
+	}
+	if out, err := wh2.Srender("convert", nil); err != nil || out != urls["convert"]+" 7x3" {
+		t.Errorf("Expected the cached %s of 7x3, but got %q, %v", urls["convert"], out, err)
+	}
+
+	rec := httptest.NewRecorder()
+	wh.StaticHandler().ServeHTTP(rec, httptest.NewRequest("GET", urls["resize"], nil))
+	if rec.Code != 200 {
+		t.Fatalf("Expected status 200 for the processed image, but got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Expected Content-Type image/png, but got %q", ct)
+	}
+	if cc := rec.Header().Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+		t.Errorf("Expected an immutable Cache-Control, but got %q", cc)
+	}
+
+	// Build writes only the images its pages use, not those of earlier
+	// renders, which StaticHandler keeps serving.
+	outDir := t.TempDir()
+	siteFS := fstest.MapFS{"content/index.md": &fstest.MapFile{Data: []byte("---\nlayout: resize\n---\n")}}
+	if err := wh.Build(context.Background(), siteFS, outDir); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	for layout, url := range urls {
+		_, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(url)))
+		if layout == "resize" && err != nil {
+			t.Errorf("Expected Build to write the %s image: %v", layout, err)
+		}
+		if layout != "resize" && !os.IsNotExist(err) {
+			t.Errorf("Expected Build not to write the %s image, but got %v", layout, err)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	wh.StaticHandler().ServeHTTP(rec, httptest.NewRequest("GET", urls["fill"], nil))
+	if rec.Code != 200 {
+		t.Errorf("Expected status 200 for an image of an earlier render, but got %d", rec.Code)
+	}
+}
+
+func TestLemur_ImageFuncs_WebP(t *testing.T) {
+	webp := fstest.MapFS{
+		"layouts/webp/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ with imageResize "img/product/il_794xN.3190196389_oxam.webp" 100 }}{{ .URL }} {{ .Width }}{{ end }}`)},
+	}
+	wh, err := lemur.NewLayered([]fs.FS{os.DirFS("themes/default"), webp}, nil, lemur.WithImageCacheDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("lemur.NewLayered failed during setup: %v", err)
+	}
+
+	out, err := wh.Srender("webp", nil)
+	if err != nil {
+		t.Fatalf("Srender failed: %v", err)
+	}
+
+	fields := strings.Fields(out)
+	if len(fields) != 2 || filepath.Ext(fields[0]) != ".jpg" || fields[1] != "100" {
+		t.Errorf("Expected the WebP as a 100 pixel wide JPEG, but got %q", out)
+	}
+}
+
+func mustJPEG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,156 @@
+package lemur
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+// layeredFS presents an ordered stack of filesystems as a single fs.FS.
+//
+// Layers are ordered from lowest to highest precedence, a file in a later
+// layer replaces the same named file in an earlier one. Directories are
+// merged, so a directory lists the union of its entries across every layer.
+type layeredFS []fs.FS
+
+// Open implements fs.FS, returning the file from the highest layer that has it.
+func (l layeredFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	for i := len(l) - 1; i >= 0; i-- {
+		f, err := l[i].Open(name)
+		if err == nil {
+			return l.openDir(name, f)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Stat implements fs.StatFS, returning the info from the highest layer that
+// has the named file.
+func (l layeredFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	for i := len(l) - 1; i >= 0; i-- {
+		info, err := fs.Stat(l[i], name)
+		if err == nil {
+			return info, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir implements fs.ReadDirFS, merging the entries of the named directory
+// from every layer. When several layers have an entry with the same name the
+// one from the highest layer is used.
+func (l layeredFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	merged := make(map[string]fs.DirEntry)
+	found := false
+
+	for i := len(l) - 1; i >= 0; i-- {
+		info, err := fs.Stat(l[i], name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		// A file in a higher layer shadows directories in the lower ones, and
+		// a directory shadows files.
+		if !info.IsDir() {
+			if !found {
+				return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+			}
+			break
+		}
+
+		entries, err := fs.ReadDir(l[i], name)
+		if err != nil {
+			return nil, err
+		}
+
+		found = true
+		for _, entry := range entries {
+			if _, ok := merged[entry.Name()]; !ok {
+				merged[entry.Name()] = entry
+			}
+		}
+	}
+
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(merged))
+	for _, entry := range merged {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	return entries, nil
+}
+
+// openDir wraps f in a layeredDir when it is a directory, so reading it lists
+// the merged entries rather than only those of the layer f came from.
+func (l layeredFS) openDir(name string, f fs.File) (fs.File, error) {
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !info.IsDir() {
+		return f, nil
+	}
+
+	entries, err := l.ReadDir(name)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &layeredDir{File: f, entries: entries}, nil
+}
+
+// layeredDir is a directory opened from a layeredFS.
+type layeredDir struct {
+	fs.File
+	entries []fs.DirEntry
+	offset  int
+}
+
+// ReadDir implements fs.ReadDirFile over the merged entries.
+func (d *layeredDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+
+	return remaining[:n], nil
+}
@@ -0,0 +1,55 @@
+package lemur
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLayeredFS(t *testing.T) {
+	base := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte("base index")},
+		"layouts/_defaults/header.html.tmpl": &fstest.MapFile{Data: []byte("base header")},
+		"layouts/shop/_index.html.tmpl":      &fstest.MapFile{Data: []byte("base shop")},
+		"layouts/shadowed":                   &fstest.MapFile{Data: []byte("a file")},
+	}
+	client := fstest.MapFS{
+		"layouts/_defaults/header.html.tmpl": &fstest.MapFile{Data: []byte("client header")},
+		"layouts/blog/_index.html.tmpl":      &fstest.MapFile{Data: []byte("client blog")},
+		"layouts/shadowed/_index.html.tmpl":  &fstest.MapFile{Data: []byte("a directory")},
+	}
+
+	lfs := layeredFS{base, client}
+
+	if err := fstest.TestFS(lfs,
+		"layouts/_defaults/_index.html.tmpl",
+		"layouts/_defaults/header.html.tmpl",
+		"layouts/shop/_index.html.tmpl",
+		"layouts/blog/_index.html.tmpl",
+		"layouts/shadowed/_index.html.tmpl",
+	); err != nil {
+		t.Fatalf("layeredFS failed fstest.TestFS: %v", err)
+	}
+
+	content, err := fs.ReadFile(lfs, "layouts/_defaults/header.html.tmpl")
+	if err != nil {
+		t.Fatalf("Expected to read overridden file, got error: %v", err)
+	}
+	if string(content) != "client header" {
+		t.Errorf("Expected the later layer to take precedence, but got %q", content)
+	}
+
+	entries, err := fs.ReadDir(lfs, "layouts")
+	if err != nil {
+		t.Fatalf("Expected to read merged layouts directory, got error: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	expected := "_defaults,blog,shadowed,shop"
+	if got := strings.Join(names, ","); got != expected {
+		t.Errorf("Expected merged entries %q, but got %q", expected, got)
+	}
+}
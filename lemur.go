@@ -6,6 +6,8 @@ import (
 	"html/template"
 	"io/fs"
 	"path/filepath"
+	"strings"
+	texttemplate "text/template"
 
 	"github.com/ukiahsmith/lemur/funcs"
 )
@@ -14,87 +16,233 @@ const (
 	LAYOUTS_DIR_PATH       = "layouts"
 	DEFAULT_TEMPLATE       = "_defaults"
 	DEFAULT_TEMPLATE_INDEX = "_index.html.tmpl"
+	TEXT_TEMPLATE_INDEX    = "_index.txt.tmpl"
+	TEXT_TEMPLATE_EXT      = ".txt.tmpl"
+	PUBLIC_DIR             = "_public"
 )
 
 type Lemur struct {
-	layouts map[string]*template.Template
-	funcs   template.FuncMap
+	layouts  map[string]*template.Template
+	text     map[string]*texttemplate.Template
+	funcs    template.FuncMap
+	ctxFuncs template.FuncMap
+	reload   *reloader
+	atomic   bool
+	minify   bool
+	fallback bool
+	aliases  map[string]string
+	site     *Site
+	markdown *funcs.MarkdownRenderer
+
+	// fsys is the theme filesystem the templates were loaded from, for
+	// features that read other theme files.
+	fsys      fs.FS
+	emailCSS  []string
+	inlineCSS bool
+	assets    *assetPipeline
+	onErr     func(error)
 }
 
-func New(templateFS fs.FS, userFuncs template.FuncMap) (Lemur, error) {
+// Option configures optional behaviour of a Lemur when passed to New or
+// NewLayered.
+type Option func(*Lemur)
+
+// WithAtomicRender makes Render and RenderTemplate execute templates into a
+// pooled buffer and copy the output to the writer only on success. A template
+// that fails halfway through then leaves the writer, e.g. an
+// http.ResponseWriter, untouched, so an error page can still be sent.
+func WithAtomicRender() Option {
+	return func(wh *Lemur) {
+		wh.atomic = true
+	}
+}
+
+// WithMinifyHTML makes Render and the other render methods minify the output
+// of HTML templates, removing comments and collapsing whitespace, see
+// minify.HTML. Text templates are left as they are. The output is buffered as
+// with WithAtomicRender, to be minified as a whole.
+func WithMinifyHTML() Option {
+	return func(wh *Lemur) {
+		wh.minify = true
+	}
+}
+
+func New(templateFS fs.FS, userFuncs template.FuncMap, opts ...Option) (Lemur, error) {
 	var wh Lemur
+	wh.assets = newAssetPipeline(templateFS)
 
 	// Initialize the Lemur instance with function maps
-	wh.initializeFuncMaps(userFuncs)
+	wh.initializeFuncMaps()
 
-	// Validate the template directory structure
-	if err := validateTemplateDirectory(templateFS); err != nil {
-		return Lemur{}, err
+	for _, opt := range opts {
+		opt(&wh)
 	}
+	wh.registerSiteFuncs()
+	wh.mergeUserFuncs(userFuncs)
 
-	// Create the base template with function map
-	tmpl := template.New("lemur").Funcs(wh.funcs)
+	if err := wh.registerContextFuncs(); err != nil {
+		return Lemur{}, err
+	}
 
-	// Process the _defaults directory first
-	tmpl, err := processDefaultsDirectory(templateFS, tmpl)
+	sets, err := loadTemplates(templateFS, wh.funcs)
 	if err != nil {
 		return Lemur{}, err
 	}
+	wh.layouts = sets.layouts
+	wh.text = sets.text
+	wh.fsys = templateFS
 
-	// Process all layout directories
-	wh.layouts, err = processLayoutDirectories(templateFS, tmpl)
-	if err != nil {
+	if err := wh.assets.load(); err != nil {
 		return Lemur{}, err
 	}
 
+	if wh.reload != nil {
+		if err := wh.reload.start(templateFS, wh.funcs, sets); err != nil {
+			return Lemur{}, err
+		}
+	}
+
 	return wh, nil
 }
 
+// NewLayered creates a Lemur from an ordered stack of theme filesystems, for
+// example a base theme, a client theme, and site specific overrides.
+//
+// Later layers take precedence: a file in layouts/_defaults or layouts/<name>
+// replaces the same named file from an earlier layer, while layouts that only
+// exist in an earlier layer remain available. The merged view is validated and
+// loaded exactly as New would load a single filesystem.
+func NewLayered(templateFSs []fs.FS, userFuncs template.FuncMap, opts ...Option) (Lemur, error) {
+	if len(templateFSs) == 0 {
+		return Lemur{}, fmt.Errorf("%w: no template filesystems supplied", ErrTemplateDir)
+	}
+
+	return New(layeredFS(templateFSs), userFuncs, opts...)
+}
+
+// templateSets holds the parsed templates of a theme: the HTML and text base
+// templates from _defaults and the layout sets cloned from them.
+type templateSets struct {
+	base     *template.Template
+	layouts  map[string]*template.Template
+	textBase *texttemplate.Template
+	text     map[string]*texttemplate.Template
+}
+
+// loadTemplates validates templateFS and parses the _defaults base templates
+// and every layout set from it. The returned base templates are never
+// executed, so they may be cloned again to rebuild a single layout set.
+func loadTemplates(templateFS fs.FS, funcMap template.FuncMap) (*templateSets, error) {
+	// Validate the template directory structure
+	if err := validateTemplateDirectory(templateFS); err != nil {
+		return nil, err
+	}
+
+	// Create the base template with function map
+	tmpl := template.New("lemur").Funcs(funcMap)
+
+	// Process the _defaults directory first, then all layout directories. A
+	// broken file does not stop the others from being attempted, so that every
+	// error in a theme is reported at once.
+	var errs LoadErrors
+
+	tmpl, err := processDefaultsDirectory(templateFS, tmpl)
+	errs.add(DEFAULT_TEMPLATE, "", err)
+
+	layouts, err := processLayoutDirectories(templateFS, tmpl)
+	var loadErrs LoadErrors
+	if err != nil && !errors.As(err, &loadErrs) {
+		return nil, err
+	}
+	errs.add("", "", err)
+
+	textBase, text, err := loadTextTemplates(templateFS, funcMap)
+	if err != nil && !errors.As(err, &loadErrs) {
+		return nil, err
+	}
+	errs.add("", "", err)
+
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+
+	return &templateSets{base: tmpl, layouts: layouts, textBase: textBase, text: text}, nil
+}
+
+// Validate loads every layout set in templateFS, as New would, and returns a
+// LoadErrors listing every template that failed to load, or nil if the theme
+// is valid. userFuncs must contain any funcs the templates call that are not
+// in the default func map.
+func Validate(templateFS fs.FS, userFuncs template.FuncMap) error {
+	var wh Lemur
+	wh.assets = newAssetPipeline(templateFS)
+	wh.initializeFuncMaps()
+	wh.registerSiteFuncs()
+	wh.mergeUserFuncs(userFuncs)
+
+	_, err := loadTemplates(templateFS, wh.funcs)
+	return err
+}
+
 // initializeFuncMaps sets up the template function maps
-func (wh *Lemur) initializeFuncMaps(userFuncs template.FuncMap) {
+func (wh *Lemur) initializeFuncMaps() {
 	wh.layouts = make(map[string]*template.Template)
+	wh.text = make(map[string]*texttemplate.Template)
 	wh.funcs = funcs.DefaultFuncMap()
+	if wh.assets != nil {
+		for k, v := range wh.assets.funcs() {
+			wh.funcs[k] = v
+		}
+	}
+}
 
-	// Merge userFuncs, user-defined funcs take precedence
+// mergeUserFuncs adds userFuncs to the function map. It runs after the
+// options, so user-defined funcs take precedence over the builtins and those
+// registered by options such as WithMarkdown and WithSite.
+func (wh *Lemur) mergeUserFuncs(userFuncs template.FuncMap) {
 	for k, v := range userFuncs {
 		wh.funcs[k] = v
 	}
 }
 
 // processDefaultsDirectory handles the special _defaults directory
+//
+// Every file is attempted, a returned error is a LoadErrors listing each file
+// that failed. The returned template holds every file that parsed.
 func processDefaultsDirectory(templateFS fs.FS, tmpl *template.Template) (*template.Template, error) {
+	var errs LoadErrors
 	defaultsDirFullPath := filepath.Join(LAYOUTS_DIR_PATH, DEFAULT_TEMPLATE)
 
-	// Read the defaults directory
-	defaultEntries, err := fs.ReadDir(templateFS, defaultsDirFullPath)
+	// Read the defaults directory tree
+	defaultFiles, err := templateFiles(templateFS, defaultsDirFullPath)
 	if err != nil {
-		return nil, fmt.Errorf("error reading _defaults directory %s from filesystem: %w", defaultsDirFullPath, err)
+		errs.add(DEFAULT_TEMPLATE, defaultsDirFullPath, fmt.Errorf("error reading _defaults directory %s from filesystem: %w", defaultsDirFullPath, err))
+		return tmpl, errs
 	}
 
 	// Parse _defaults/_index.html.tmpl first, if it exists, into the base tmpl
-	tmpl, err = processDefaultsIndexTemplate(templateFS, tmpl, LAYOUTS_DIR_PATH)
-	if err != nil {
-		return nil, err
+	if parsed, err := processDefaultsIndexTemplate(templateFS, tmpl, LAYOUTS_DIR_PATH); err != nil {
+		errs.add(DEFAULT_TEMPLATE, filepath.Join(defaultsDirFullPath, DEFAULT_TEMPLATE_INDEX), err)
+	} else {
+		tmpl = parsed
 	}
 
-	// Process all other files in _defaults
-	for _, de := range defaultEntries {
-		if de.IsDir() {
-			continue
-		}
-		fileName := de.Name()
-		if fileName == DEFAULT_TEMPLATE_INDEX || fileName[0] == '.' {
+	// Process all other files in _defaults, including those in subdirectories
+	for _, fileName := range defaultFiles {
+		if fileName == DEFAULT_TEMPLATE_INDEX || isTextTemplate(fileName) {
 			continue
 		}
 
 		defaultFileRelPath := filepath.Join(LAYOUTS_DIR_PATH, DEFAULT_TEMPLATE, fileName)
-		tmpl, err = parseTemplateFile(templateFS, tmpl, defaultFileRelPath, filepath.Base(defaultFileRelPath))
+		parsed, err := parseTemplateFile(templateFS, tmpl, defaultFileRelPath, fileName)
 		if err != nil {
-			return nil, fmt.Errorf("failed to process default template file %s: %w", defaultFileRelPath, err)
+			errs.add(DEFAULT_TEMPLATE, defaultFileRelPath, fmt.Errorf("failed to process default template file %s: %w", defaultFileRelPath, err))
+			continue
 		}
+		tmpl = parsed
 	}
 
-	return tmpl, nil
+	return tmpl, errs.err()
 }
 
 // processDefaultsIndexTemplate handles the special _defaults/_index.html.tmpl file
@@ -126,7 +274,11 @@ func parseTemplateFile(templateFS fs.FS, tmpl *template.Template, filePath strin
 }
 
 // processLayoutDirectories handles all layout directories and their templates
+//
+// Every layout is attempted, a returned error is a LoadErrors listing each file
+// that failed in any layout.
 func processLayoutDirectories(templateFS fs.FS, baseTmpl *template.Template) (map[string]*template.Template, error) {
+	var errs LoadErrors
 	layoutMap := make(map[string]*template.Template)
 
 	// Get all entries in the layouts directory
@@ -145,28 +297,33 @@ func processLayoutDirectories(templateFS fs.FS, baseTmpl *template.Template) (ma
 		}
 
 		name := entry.Name() // This is the layout name, e.g., "_defaults", "mytemplate"
-		if name[0] == '.' {
+		if !isLayoutDir(name) {
 			continue
 		}
 
 		// Process a single layout directory
 		tmpl, err := processLayoutDirectory(templateFS, baseTmpl, LAYOUTS_DIR_PATH, name)
 		if err != nil {
-			return nil, err
+			errs.add(name, filepath.Join(LAYOUTS_DIR_PATH, name), err)
+			continue
 		}
 
 		layoutMap[name] = tmpl
 	}
 
-	return layoutMap, nil
+	return layoutMap, errs.err()
 }
 
 // processLayoutDirectory handles a single layout directory and its templates
+//
+// Every file is attempted, a returned error is a LoadErrors listing each file
+// that failed.
 func processLayoutDirectory(templateFS fs.FS, baseTmpl *template.Template, layoutsDirPath string, layoutName string) (*template.Template, error) {
+	var errs LoadErrors
 	currentLayoutPathRel := filepath.Join(layoutsDirPath, layoutName)
 
-	// Read all files in this layout directory
-	tmplEntries, err := fs.ReadDir(templateFS, currentLayoutPathRel)
+	// Read all files in this layout directory tree
+	tmplFiles, err := templateFiles(templateFS, currentLayoutPathRel)
 	if err != nil {
 		return nil, fmt.Errorf("error reading directory for template set %s from filesystem: %w", layoutName, err)
 	}
@@ -184,28 +341,76 @@ func processLayoutDirectory(templateFS fs.FS, baseTmpl *template.Template, layou
 	}
 
 	// Process index template if it exists
-	ctmpl, err = processLayoutIndexTemplate(templateFS, ctmpl, layoutsDirPath, layoutName)
-	if err != nil {
-		return nil, err
+	if parsed, err := processLayoutIndexTemplate(templateFS, ctmpl, layoutsDirPath, layoutName); err != nil {
+		errs.add(layoutName, filepath.Join(currentLayoutPathRel, DEFAULT_TEMPLATE_INDEX), err)
+	} else {
+		ctmpl = parsed
 	}
 
-	// Process all other template files in this layout
-	for _, tmplEntry := range tmplEntries {
-		tmplFileName := tmplEntry.Name()
-		if tmplFileName[0] == '.' || tmplFileName == DEFAULT_TEMPLATE_INDEX {
+	// Process all other template files in this layout, including those in subdirectories
+	for _, tmplFileName := range tmplFiles {
+		if tmplFileName == DEFAULT_TEMPLATE_INDEX || isTextTemplate(tmplFileName) {
 			continue
 		}
 
 		filePathToParseRel := filepath.Join(layoutsDirPath, layoutName, tmplFileName)
-		ctmpl, err = parseTemplateFile(templateFS, ctmpl, filePathToParseRel, filepath.Base(filePathToParseRel))
+		parsed, err := parseTemplateFile(templateFS, ctmpl, filePathToParseRel, tmplFileName)
 		if err != nil {
-			return nil, fmt.Errorf("error processing file %s in template set %s: %w", tmplFileName, layoutName, err)
+			errs.add(layoutName, filePathToParseRel, fmt.Errorf("error processing file %s in template set %s: %w", tmplFileName, layoutName, err))
+			continue
 		}
+		ctmpl = parsed
+	}
+
+	if err := errs.err(); err != nil {
+		return nil, err
 	}
 
 	return ctmpl, nil
 }
 
+// isLayoutDir reports whether the directory name below layouts/ is a layout
+// set. Hidden directories and _public, which holds files served as is, are
+// not.
+func isLayoutDir(name string) bool {
+	return name[0] != '.' && name != PUBLIC_DIR
+}
+
+// templateFiles returns the paths of every template file below dirPath,
+// relative to dirPath and in lexical order. Files and directories whose names
+// start with a '.' are skipped. The relative path is the name a template is
+// addressed by, e.g. "card.html.tmpl" or "partials/card.html.tmpl".
+func templateFiles(templateFS fs.FS, dirPath string) ([]string, error) {
+	var files []string
+
+	err := fs.WalkDir(templateFS, dirPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dirPath {
+			return nil
+		}
+
+		if d.Name()[0] == '.' {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		files = append(files, strings.TrimPrefix(p, dirPath+"/"))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
 // ensureDefaultBlockStructure ensures the template has the required block structure
 func ensureDefaultBlockStructure(tmpl *template.Template, layoutName string) (*template.Template, error) {
 	if tmpl.Lookup(DEFAULT_TEMPLATE_INDEX) == nil {
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/ukiahsmith/lemur"
 )
@@ -74,6 +75,14 @@ full_dir atmpl.html.tmpl
 			`full_dir other_tmpl _index.html.tmpl
 full_dir atmpl.html.tmpl
 Author: Name Name
+`,
+		},
+		{
+			"nested mytemplate",
+			os.DirFS("testdata/nested"),
+			"mytemplate",
+			`nested _defaults partials/header.html.tmpl
+nested mytemplate partials/cards/card.html.tmpl
 `,
 		},
 	}
@@ -143,3 +152,129 @@ func Test_New_ShouldErr(t *testing.T) {
 		})
 	}
 }
+
+func Test_NewLayered(t *testing.T) {
+	base := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ template "header.html.tmpl" . }} {{ block "main.html.tmpl" . }}{{ end }}`)},
+		"layouts/_defaults/header.html.tmpl": &fstest.MapFile{Data: []byte("base header")},
+		"layouts/shop/main.html.tmpl":        &fstest.MapFile{Data: []byte("base shop")},
+		"layouts/blog/main.html.tmpl":        &fstest.MapFile{Data: []byte("base blog")},
+	}
+	client := fstest.MapFS{
+		"layouts/_defaults/header.html.tmpl": &fstest.MapFile{Data: []byte("client header")},
+		"layouts/shop/main.html.tmpl":        &fstest.MapFile{Data: []byte("client shop")},
+	}
+	site := fstest.MapFS{
+		"layouts/shop/main.html.tmpl": &fstest.MapFile{Data: []byte("site shop")},
+	}
+
+	dataTemplate := []struct {
+		Name     string
+		TmplName string
+		Expected string
+	}{
+		{"Overridden by every layer", "shop", "client header site shop"},
+		{"Only present in base", "blog", "client header base blog"},
+		{"Defaults", "", "client header "},
+	}
+
+	tmplObj, err := lemur.NewLayered([]fs.FS{base, client, site}, nil)
+	if err != nil {
+		t.Fatalf("Test_NewLayered: lemur.NewLayered failed: %s", err)
+	}
+
+	for _, d := range dataTemplate {
+		t.Run(d.Name, func(t *testing.T) {
+			maybe, err := tmplObj.Srender(d.TmplName, nil)
+			if err != nil {
+				t.Fatalf("Test_NewLayered, %s: Srender failed: %s", d.Name, err)
+			}
+
+			if maybe != d.Expected {
+				t.Errorf("Test_NewLayered, %s: failed, maybe expected %q but got %q", d.Name, d.Expected, maybe)
+			}
+		})
+	}
+}
+
+func Test_NewLayered_ShouldErr(t *testing.T) {
+	// No single layer has both layouts and _defaults, but the merged view does.
+	_, err := lemur.NewLayered([]fs.FS{
+		fstest.MapFS{"layouts/shop/main.html.tmpl": &fstest.MapFile{Data: []byte("shop")}},
+		fstest.MapFS{"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte("index")}},
+	}, nil)
+	if err != nil {
+		t.Errorf("Test_NewLayered_ShouldErr: expected merged layers to validate, received %s", err)
+	}
+
+	_, err = lemur.NewLayered(nil, nil)
+	if !errors.Is(err, lemur.ErrTemplateDir) {
+		t.Errorf("Test_NewLayered_ShouldErr: expected error to wrap %v, but got %v", lemur.ErrTemplateDir, err)
+	}
+
+	_, err = lemur.NewLayered([]fs.FS{
+		fstest.MapFS{"layouts/shop/main.html.tmpl": &fstest.MapFile{Data: []byte("shop")}},
+	}, nil)
+	if !errors.Is(err, lemur.ErrTemplateDir) {
+		t.Errorf("Test_NewLayered_ShouldErr: expected error to wrap %v, but got %v", lemur.ErrTemplateDir, err)
+	}
+}
+
+func Test_New_LoadErrors(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl":   &fstest.MapFile{Data: []byte(`{{ block "main.html.tmpl" . }}{{ end }}`)},
+		"layouts/_defaults/header.html.tmpl":   &fstest.MapFile{Data: []byte("header\n{{ .Title ")},
+		"layouts/shop/main.html.tmpl":          &fstest.MapFile{Data: []byte("shop\n\n{{ end }}")},
+		"layouts/shop/partials/card.html.tmpl": &fstest.MapFile{Data: []byte("{{ nosuchfunc }}")},
+		"layouts/blog/main.html.tmpl":          &fstest.MapFile{Data: []byte("blog is fine")},
+	}
+
+	expected := []struct {
+		Layout string
+		Path   string
+		Line   int
+	}{
+		{"_defaults", "layouts/_defaults/header.html.tmpl", 2},
+		{"shop", "layouts/shop/main.html.tmpl", 3},
+		{"shop", "layouts/shop/partials/card.html.tmpl", 1},
+	}
+
+	for _, validate := range []struct {
+		Name string
+		Fn   func() error
+	}{
+		{"New", func() error { _, err := lemur.New(templateFS, nil); return err }},
+		{"Validate", func() error { return lemur.Validate(templateFS, nil) }},
+	} {
+		t.Run(validate.Name, func(t *testing.T) {
+			err := validate.Fn()
+			if err == nil {
+				t.Fatalf("Expected an error, received no error")
+			}
+
+			if !errors.Is(err, lemur.ErrTemplateDir) {
+				t.Errorf("Expected error to wrap %v, but it did not. Got: %v", lemur.ErrTemplateDir, err)
+			}
+
+			var loadErrs lemur.LoadErrors
+			if !errors.As(err, &loadErrs) {
+				t.Fatalf("Expected error to be a lemur.LoadErrors, got %T", err)
+			}
+
+			if len(loadErrs) != len(expected) {
+				t.Fatalf("Expected %d load errors, got %d: %v", len(expected), len(loadErrs), err)
+			}
+
+			for i, e := range expected {
+				le := loadErrs[i]
+				if le.Layout != e.Layout || le.Path != e.Path || le.Line != e.Line {
+					t.Errorf("Expected load error %d at %s %s:%d, got %s %s:%d", i, e.Layout, e.Path, e.Line, le.Layout, le.Path, le.Line)
+				}
+			}
+		})
+	}
+
+	if err := lemur.Validate(os.DirFS("testdata/full_dir"), nil); err != nil {
+		t.Errorf("Expected a valid theme to validate, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package lemur
+
+import "github.com/ukiahsmith/lemur/funcs"
+
+// WithMarkdown registers md as the "markdown" template func, replacing the
+// default renderer, unless the user funcs passed to New have their own.
+// Conversion errors fail the render. Build renders Markdown content with md
+// either way.
+func WithMarkdown(md *funcs.MarkdownRenderer) Option {
+	return func(wh *Lemur) {
+		wh.funcs["markdown"] = md.Render
+		wh.markdown = md
+	}
+}
+
+// markdownRenderer returns the renderer set with WithMarkdown, or the shared
+// default renderer, which omits raw HTML.
+func (wh *Lemur) markdownRenderer() *funcs.MarkdownRenderer {
+	if wh.markdown == nil {
+		return funcs.DefaultMarkdownRenderer()
+	}
+
+	return wh.markdown
+}
+
+// WithSanitizer registers s as the "sanitizeHTML" template func, replacing
+// the default UGCPolicy sanitizer, unless the user funcs passed to New have
+// their own. To also sanitize Markdown output, build the renderer passed to
+// WithMarkdown with funcs.MarkdownSanitize.
+func WithSanitizer(s *funcs.Sanitizer) Option {
+	return func(wh *Lemur) {
+		wh.funcs["sanitizeHTML"] = s.Sanitize
+	}
+}
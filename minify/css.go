@@ -0,0 +1,123 @@
+package minify
+
+import (
+	"bytes"
+	"strings"
+)
+
+// CSS returns the stylesheet src without comments and superfluous whitespace,
+// and without the last semicolon of each block. Strings and url() values are
+// kept as they are.
+func CSS(src []byte) []byte {
+	out := make([]byte, 0, len(src))
+	space := false
+
+	// noSpaceAfter and noSpaceBefore are the bytes whitespace can be dropped
+	// after and before. A space before ':' in a selector separates a
+	// pseudo-class from its compound selector, and '+', '-' and '*' need it in
+	// calc(), so those keep it.
+	const noSpaceAfter = "{};:,>(~"
+	const noSpaceBefore = "{};,>)~!"
+
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		switch {
+		case isSpace(c):
+			space = true
+			i++
+			continue
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			n := comment(src[i:])
+			if i+2 < len(src) && src[i+2] == '!' {
+				if space && len(out) > 0 && strings.IndexByte(noSpaceAfter, out[len(out)-1]) < 0 {
+					out = append(out, ' ')
+				}
+				out = append(out, src[i:i+n]...)
+				space = false
+			} else {
+				space = true
+			}
+			i += n
+			continue
+		}
+
+		if space && len(out) > 0 && strings.IndexByte(noSpaceAfter, out[len(out)-1]) < 0 && strings.IndexByte(noSpaceBefore, c) < 0 && !(c == ':' && isDeclaration(src[i:])) {
+			out = append(out, ' ')
+		}
+		space = false
+
+		switch {
+		case c == '"' || c == '\'':
+			n, _ := quoted(src[i:])
+			out = append(out, src[i:i+n]...)
+			i += n
+		case c == '}':
+			if len(out) > 0 && out[len(out)-1] == ';' {
+				out = out[:len(out)-1]
+			}
+			out = append(out, c)
+			i++
+		case (c == 'u' || c == 'U') && hasPrefixFold(src[i:], "url(") && !isNameByte(out):
+			n := urlValue(src[i:])
+			out = append(out, src[i:i+n]...)
+			i += n
+		default:
+			out = append(out, c)
+			i++
+		}
+	}
+
+	return out
+}
+
+// isDeclaration reports whether the ':' starting src separates a property
+// from its value, rather than starting a pseudo-class of a selector.
+func isDeclaration(src []byte) bool {
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case '"', '\'':
+			n, _ := quoted(src[i:])
+			i += n - 1
+		case '{':
+			return false
+		case ';', '}':
+			return true
+		}
+	}
+
+	return true
+}
+
+// isNameByte reports whether out ends in a byte of a CSS name, so that a
+// following "url(" is only the end of a longer function name.
+func isNameByte(out []byte) bool {
+	if len(out) == 0 {
+		return false
+	}
+	c := out[len(out)-1]
+
+	return c == '-' || c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+func hasPrefixFold(src []byte, prefix string) bool {
+	return len(src) >= len(prefix) && bytes.EqualFold(src[:len(prefix)], []byte(prefix))
+}
+
+// urlValue returns the length of the url() value at the start of src, which
+// may hold an unquoted URL with "//" or "/*" in it.
+func urlValue(src []byte) int {
+	for i := 4; i < len(src); i++ {
+		switch src[i] {
+		case '"', '\'':
+			n, _ := quoted(src[i:])
+			i += n - 1
+		case '\\':
+			i++
+		case ')':
+			return i + 1
+		}
+	}
+
+	return len(src)
+}
@@ -0,0 +1,216 @@
+package minify
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// blockElements are the elements whitespace around which is not rendered.
+var blockElements = map[string]bool{
+	"html": true, "head": true, "body": true, "title": true, "meta": true,
+	"link": true, "base": true, "script": true, "style": true, "noscript": true,
+	"template": true, "address": true, "article": true, "aside": true,
+	"blockquote": true, "details": true, "dialog": true, "summary": true,
+	"div": true, "dl": true, "dd": true, "dt": true, "fieldset": true,
+	"legend": true, "figure": true, "figcaption": true, "footer": true,
+	"form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "header": true, "hgroup": true, "hr": true, "li": true,
+	"main": true, "nav": true, "ol": true, "ul": true, "p": true, "pre": true,
+	"section": true, "table": true, "caption": true, "colgroup": true,
+	"col": true, "thead": true, "tbody": true, "tfoot": true, "tr": true,
+	"td": true, "th": true, "option": true, "optgroup": true, "select": true,
+}
+
+// HTML returns the document src without comments and with whitespace
+// collapsed, keeping the content of pre and textarea elements as it is. The
+// whitespace between the attributes of a tag is collapsed, and whitespace
+// around block elements such as div and p is removed. Inline stylesheets and
+// scripts are minified with CSS and JS, a script that is not JavaScript, e.g.
+// JSON, or that fails to minify is kept as it is.
+//
+// Conditional comments, "<!--[if IE]>", are kept.
+func HTML(src []byte) []byte {
+	z := html.NewTokenizer(bytes.NewReader(src))
+	out := make([]byte, 0, len(src))
+
+	var (
+		pre     int    // depth of pre and textarea elements
+		raw     string // the kind of the content of a style or script element, see rawKind
+		space   bool   // whitespace is pending before the next token
+		inBlock bool   // no text since the last block element tag
+	)
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			// The input is a byte slice, so the only error is io.EOF.
+			return out
+		}
+		token := append([]byte(nil), z.Raw()...)
+
+		switch tt {
+		case html.TextToken:
+			switch {
+			case pre > 0:
+				out = append(out, token...)
+				inBlock = false
+			case raw != "":
+				out = append(out, minifyRaw(raw, token)...)
+			default:
+				text := collapseSpace(token)
+				if text[0] == ' ' {
+					space = true
+					text = text[1:]
+				}
+				if len(text) == 0 {
+					continue
+				}
+				if space && !inBlock && !endsWithSpace(out) {
+					out = append(out, ' ')
+				}
+				space = false
+				if text[len(text)-1] == ' ' {
+					space = true
+					text = text[:len(text)-1]
+				}
+				out = append(out, text...)
+				inBlock = false
+			}
+			raw = ""
+			continue
+
+		case html.CommentToken:
+			data := string(z.Text())
+			if strings.HasPrefix(data, "[if") || strings.HasPrefix(data, "<![endif") {
+				out = append(out, token...)
+			}
+			continue
+
+		case html.DoctypeToken:
+			out = append(out, token...)
+			continue
+		}
+
+		name, hasAttr := z.TagName()
+		tag := string(name)
+		block := blockElements[tag]
+		if space && !block && !inBlock {
+			out = append(out, ' ')
+		}
+		space = false
+		inBlock = block
+		raw = ""
+
+		switch tt {
+		case html.StartTagToken:
+			if tag == "pre" || tag == "textarea" {
+				pre++
+			}
+			if tag == "style" || tag == "script" {
+				raw = rawKind(tag, z, hasAttr)
+			}
+		case html.EndTagToken:
+			if (tag == "pre" || tag == "textarea") && pre > 0 {
+				pre--
+			}
+		}
+
+		out = append(out, collapseTag(token)...)
+	}
+}
+
+// rawKind returns "css" or "js" when the style or script element, whose
+// attributes are next in z, holds CSS or JavaScript, and "data" otherwise.
+func rawKind(tag string, z *html.Tokenizer, hasAttr bool) string {
+	typ := ""
+	for hasAttr {
+		var key, val []byte
+		key, val, hasAttr = z.TagAttr()
+		if string(key) == "type" {
+			typ = strings.ToLower(strings.TrimSpace(string(val)))
+		}
+	}
+
+	if tag == "style" {
+		if typ == "" || typ == "text/css" {
+			return "css"
+		}
+		return "data"
+	}
+	if typ == "" || typ == "module" || strings.Contains(typ, "javascript") || strings.Contains(typ, "ecmascript") {
+		return "js"
+	}
+
+	return "data"
+}
+
+// minifyRaw minifies the content of a style or script element of the kind
+// raw.
+func minifyRaw(raw string, content []byte) []byte {
+	switch raw {
+	case "css":
+		return CSS(content)
+	case "data":
+		return content
+	}
+
+	b, err := JS(content)
+	if err != nil {
+		return content
+	}
+
+	return b
+}
+
+// collapseSpace replaces every run of whitespace in text with one space.
+func collapseSpace(text []byte) []byte {
+	out := make([]byte, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		if !isSpace(text[i]) {
+			out = append(out, text[i])
+			continue
+		}
+		out = append(out, ' ')
+		for i+1 < len(text) && isSpace(text[i+1]) {
+			i++
+		}
+	}
+
+	return out
+}
+
+// collapseTag collapses the whitespace between the attributes of a tag, and
+// removes it before the closing "/>" or ">".
+func collapseTag(tag []byte) []byte {
+	out := make([]byte, 0, len(tag))
+	var quote byte
+
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case isSpace(c):
+			for i+1 < len(tag) && isSpace(tag[i+1]) {
+				i++
+			}
+			if next := tag[i+1:]; len(next) > 0 && next[0] != '>' && !bytes.HasPrefix(next, []byte("/>")) {
+				out = append(out, ' ')
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+
+	return out
+}
+
+func endsWithSpace(out []byte) bool {
+	return len(out) > 0 && isSpace(out[len(out)-1])
+}
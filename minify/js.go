@@ -0,0 +1,329 @@
+package minify
+
+import "strings"
+
+// regexpKeywords are the keywords after which a '/' starts a regular
+// expression rather than a division.
+var regexpKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "case": true,
+	"do": true, "else": true, "yield": true, "await": true,
+}
+
+// headKeywords are the statements whose parenthesized head is followed by a
+// statement, so a '/' after the closing ')' starts a regular expression.
+var headKeywords = map[string]bool{"if": true, "while": true, "for": true, "with": true}
+
+// JS returns the script src without comments and superfluous whitespace.
+//
+// Line breaks are kept where automatic semicolon insertion may depend on
+// them, so code without semicolons still works. Strings, template literals
+// and regular expressions are kept as they are. An error is returned for an
+// unterminated string, template literal, regular expression or comment.
+func JS(src []byte) ([]byte, error) {
+	m := jsMinifier{src: src, out: make([]byte, 0, len(src))}
+	if _, err := m.code(0, false); err != nil {
+		return nil, err
+	}
+
+	return m.out, nil
+}
+
+type jsMinifier struct {
+	src []byte
+	out []byte
+
+	space   bool // whitespace was skipped since the last token
+	newline bool // the skipped whitespace had a line break
+
+	// brackets holds, for each open bracket, whether a '/' after its closing
+	// bracket starts a regular expression: after the ')' of an if, while, for
+	// or with head, and after the '}' of a block. closedRegexp is that of the
+	// last closing bracket written.
+	brackets     []bool
+	closedRegexp bool
+}
+
+// code minifies the code starting at src[i] and returns the offset after it.
+// In the substitution of a template literal, inTemplate, the code ends with
+// the '}' closing the substitution.
+func (m *jsMinifier) code(i int, inTemplate bool) (int, error) {
+	src := m.src
+	depth := 0
+
+	for i < len(src) {
+		c := src[i]
+
+		switch {
+		case isSpace(c):
+			m.space = true
+			if c == '\n' || c == '\r' {
+				m.newline = true
+			}
+			i++
+			continue
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			m.space = true
+			continue
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			n := comment(src[i:])
+			if n < 4 || src[i+n-2] != '*' || src[i+n-1] != '/' {
+				return 0, &Error{Line: lineAt(src, i), Msg: "unterminated comment"}
+			}
+			if i+2 < len(src) && src[i+2] == '!' {
+				m.separate('/')
+				m.out = append(m.out, src[i:i+n]...)
+				m.space, m.newline = true, true
+			} else {
+				m.space = true
+				if strings.ContainsAny(string(src[i:i+n]), "\n\r") {
+					m.newline = true
+				}
+			}
+			i += n
+			continue
+		}
+
+		newline := m.newline
+		m.separate(c)
+
+		switch {
+		case c == '"' || c == '\'':
+			n, ok := quoted(src[i:])
+			if !ok {
+				return 0, &Error{Line: lineAt(src, i), Msg: "unterminated string"}
+			}
+			m.out = append(m.out, src[i:i+n]...)
+			i += n
+		case c == '`':
+			end, err := m.template(i)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+		case c == '/' && m.regexpAllowed(newline, src[i:]):
+			n, ok := regexpLiteral(src[i:])
+			if !ok {
+				return 0, &Error{Line: lineAt(src, i), Msg: "unterminated regular expression"}
+			}
+			m.out = append(m.out, src[i:i+n]...)
+			i += n
+		case c == '(':
+			m.brackets = append(m.brackets, headKeywords[lastWord(m.out)])
+			m.out = append(m.out, c)
+			i++
+		case c == '[':
+			m.brackets = append(m.brackets, false)
+			m.out = append(m.out, c)
+			i++
+		case c == '{':
+			depth++
+			m.brackets = append(m.brackets, m.blockAllowed())
+			m.out = append(m.out, c)
+			i++
+		case c == '}' && depth == 0 && inTemplate:
+			m.out = append(m.out, c)
+			return i + 1, nil
+		case c == ')' || c == ']' || c == '}':
+			if c == '}' {
+				depth--
+			}
+			m.closedRegexp = false
+			if n := len(m.brackets); n > 0 {
+				m.closedRegexp = m.brackets[n-1]
+				m.brackets = m.brackets[:n-1]
+			}
+			m.out = append(m.out, c)
+			i++
+		default:
+			m.out = append(m.out, c)
+			i++
+		}
+	}
+
+	if inTemplate {
+		return 0, &Error{Line: lineAt(src, len(src)), Msg: "unterminated template literal"}
+	}
+
+	return i, nil
+}
+
+// template copies the template literal starting at src[i], minifying the code
+// of its substitutions, and returns the offset after it.
+func (m *jsMinifier) template(i int) (int, error) {
+	src := m.src
+	start := i
+	m.out = append(m.out, '`')
+
+	for i++; i < len(src); i++ {
+		switch {
+		case src[i] == '\\':
+			m.out = append(m.out, src[i])
+			if i+1 < len(src) {
+				i++
+				m.out = append(m.out, src[i])
+			}
+		case src[i] == '`':
+			m.out = append(m.out, '`')
+			return i + 1, nil
+		case src[i] == '$' && i+1 < len(src) && src[i+1] == '{':
+			m.out = append(m.out, "${"...)
+			m.space, m.newline = false, false
+			end, err := m.code(i+2, true)
+			if err != nil {
+				return 0, err
+			}
+			i = end - 1
+		default:
+			m.out = append(m.out, src[i])
+		}
+	}
+
+	return 0, &Error{Line: lineAt(src, start), Msg: "unterminated template literal"}
+}
+
+// separate writes the whitespace skipped before the token starting with c
+// when it is needed: a space between two words or between operators that
+// would otherwise merge, and a line break wherever it may end a statement.
+func (m *jsMinifier) separate(c byte) {
+	space, newline := m.space, m.newline
+	m.space, m.newline = false, false
+	if !space || len(m.out) == 0 {
+		return
+	}
+	last := m.out[len(m.out)-1]
+
+	if newline {
+		if strings.IndexByte("{};,([", last) < 0 && strings.IndexByte("})];,.", c) < 0 {
+			m.out = append(m.out, '\n')
+			return
+		}
+	}
+
+	switch {
+	case isWordByte(last) && isWordByte(c),
+		last == '+' && c == '+',
+		last == '-' && c == '-',
+		last == '/' && c == '/',
+		isDigit(last) && c == '.':
+		m.out = append(m.out, ' ')
+	}
+}
+
+// blockAllowed reports whether a '{' after the output so far opens a block
+// rather than an object literal: at the start of a statement, after the head
+// of a statement or function, after "=>", and after a word that does not start
+// an expression, such as else or a class name.
+func (m *jsMinifier) blockAllowed() bool {
+	out := m.out
+	for len(out) > 0 && isSpace(out[len(out)-1]) {
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return true
+	}
+
+	last := out[len(out)-1]
+	switch {
+	case strings.IndexByte("{};)", last) >= 0:
+		return true
+	case last == '>' && len(out) >= 2 && out[len(out)-2] == '=':
+		return true
+	case isWordByte(last):
+		word := lastWord(out)
+		return !regexpKeywords[word] || word == "else" || word == "do"
+	}
+
+	return false
+}
+
+// regexpAllowed reports whether a '/' after the output so far, at the start of
+// src, starts a regular expression literal. newline is whether a line break
+// came before it.
+func (m *jsMinifier) regexpAllowed(newline bool, src []byte) bool {
+	out := m.out
+	for len(out) > 0 && isSpace(out[len(out)-1]) {
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return true
+	}
+
+	last := out[len(out)-1]
+	if last == ')' || last == ']' || last == '}' {
+		if m.closedRegexp {
+			return true
+		}
+		// On a new line the '/' continues the expression, but was likely
+		// meant to start a statement. A regular expression is copied as it
+		// is, so it is the safe reading when the rest of the line is one.
+		if newline {
+			_, ok := regexpLiteral(src)
+			return ok
+		}
+		return false
+	}
+
+	// A postfix ++ or -- ends an expression, "i++ / 2" is a division.
+	if n := len(out); n >= 2 && (last == '+' || last == '-') && out[n-2] == last {
+		before := out[:n-2]
+		for len(before) > 0 && isSpace(before[len(before)-1]) {
+			before = before[:len(before)-1]
+		}
+		if len(before) > 0 {
+			c := before[len(before)-1]
+			return !(c == ')' || c == ']' || isWordByte(c) && !regexpKeywords[lastWord(before)])
+		}
+		return true
+	}
+	if !isWordByte(last) {
+		return true
+	}
+
+	return regexpKeywords[lastWord(out)]
+}
+
+// lastWord returns the word that out ends with.
+func lastWord(out []byte) string {
+	start := len(out)
+	for start > 0 && isWordByte(out[start-1]) {
+		start--
+	}
+
+	return string(out[start:])
+}
+
+// regexpLiteral returns the length of the regular expression literal at the
+// start of src, without its flags, which are copied as a word.
+func regexpLiteral(src []byte) (int, bool) {
+	class := false
+	for i := 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '\n':
+			return i, false
+		case '[':
+			class = true
+		case ']':
+			class = false
+		case '/':
+			if !class {
+				return i + 1, true
+			}
+		}
+	}
+
+	return len(src), false
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c == '\\' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,68 @@
+// Package minify removes the comments and whitespace of CSS, JavaScript and
+// HTML that a browser does not need.
+//
+// The minifiers are deliberately conservative: they never rename, reorder or
+// rewrite code, so their output behaves exactly as the input did. Comments
+// starting with "/*!", by convention licence notices, are kept.
+package minify
+
+import "fmt"
+
+// Error is a syntax error that stops a minifier, reported at the 1-based line
+// of the input it was found on.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// isSpace reports whether c is whitespace in CSS, JavaScript and HTML.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// lineAt returns the 1-based line of the offset i in src.
+func lineAt(src []byte, i int) int {
+	line := 1
+	for _, c := range src[:i] {
+		if c == '\n' {
+			line++
+		}
+	}
+
+	return line
+}
+
+// comment returns the length of the /* */ comment at the start of src, or the
+// length of src when it is not closed.
+func comment(src []byte) int {
+	for i := 2; i+1 < len(src); i++ {
+		if src[i] == '*' && src[i+1] == '/' {
+			return i + 2
+		}
+	}
+
+	return len(src)
+}
+
+// quoted returns the length of the string literal at the start of src, which
+// starts with its quote, and false when a newline or the end of src comes
+// before the closing quote.
+func quoted(src []byte) (int, bool) {
+	quote := src[0]
+	for i := 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '\n':
+			return i, false
+		case quote:
+			return i + 1, true
+		}
+	}
+
+	return len(src), false
+}
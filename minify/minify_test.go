@@ -0,0 +1,179 @@
+package minify_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ukiahsmith/lemur/minify"
+)
+
+func TestCSS(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Source   string
+		Expected string
+	}{
+		{
+			Name:     "whitespace and last semicolon",
+			Source:   "a {\n  color : red ;\n  margin: 0 auto;\n}\n\n.b > .c ,\n.d ~ .e { width: 100% !important; }\n",
+			Expected: "a{color:red;margin:0 auto}.b>.c,.d~.e{width:100%!important}",
+		},
+		{
+			Name:     "comments",
+			Source:   "/*! licence */\n/* dropped */ a { color: red; /* dropped */ }",
+			Expected: "/*! licence */ a{color:red}",
+		},
+		{
+			Name:     "pseudo-class after a descendant combinator",
+			Source:   ".nav :hover { color: red; } a:not( .b ) { color: blue; }",
+			Expected: ".nav :hover{color:red}a:not(.b){color:blue}",
+		},
+		{
+			Name:     "at-rules and calc",
+			Source:   "@media screen and (min-width: 640px) {\n  .a { width: calc(100% - 2rem); }\n}",
+			Expected: "@media screen and (min-width:640px){.a{width:calc(100% - 2rem)}}",
+		},
+		{
+			Name:     "strings and urls",
+			Source:   "a::before { content: \"a  ;  b\"; background: url(http://example.com/*.png); }",
+			Expected: "a::before{content:\"a  ;  b\";background:url(http://example.com/*.png)}",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if out := string(minify.CSS([]byte(tc.Source))); out != tc.Expected {
+				t.Errorf("Expected %q, but got %q", tc.Expected, out)
+			}
+		})
+	}
+}
+
+func TestJS(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Source   string
+		Expected string
+	}{
+		{
+			Name:     "whitespace and comments",
+			Source:   "// comment\nfunction add ( a, b ) {\n  /* block */\n  return a + b; // trailing\n}\n",
+			Expected: "function add(a,b){return a+b;}",
+		},
+		{
+			Name:     "line breaks without semicolons",
+			Source:   "const a = 1\nlet b = a\n++b\nreturn\nb",
+			Expected: "const a=1\nlet b=a\n++b\nreturn\nb",
+		},
+		{
+			Name:     "operators that would merge",
+			Source:   "x = a + +b - -c",
+			Expected: "x=a+ +b- -c",
+		},
+		{
+			Name:     "strings",
+			Source:   "say( 'it is  // not a comment' , \"/* nor */\" )",
+			Expected: "say('it is  // not a comment',\"/* nor */\")",
+		},
+		{
+			Name:     "regular expressions and division",
+			Source:   "if ( /a b\\/[/]/g.test( s ) ) { x = a / 2 / b }\nreturn /c d/",
+			Expected: "if(/a b\\/[/]/g.test(s)){x=a/2/b}return/c d/",
+		},
+		{
+			Name:     "division after postfix increment",
+			Source:   "var h = i++ / 2;\nvar k = a[0]-- / b;\nvar m = f() / 3",
+			Expected: "var h=i++/2;var k=a[0]--/b;var m=f()/3",
+		},
+		{
+			Name:     "regular expression after prefix increment",
+			Source:   "x = ++ /a/.lastIndex\nreturn /b c/",
+			Expected: "x=++/a/.lastIndex\nreturn/b c/",
+		},
+		{
+			Name:     "regular expression after a statement head",
+			Source:   "if (ok) /https?:\\/\\//.test(u); f()\nwhile (x) /a  b/.exec(s)",
+			Expected: "if(ok)/https?:\\/\\//.test(u);f()\nwhile(x)/a  b/.exec(s)",
+		},
+		{
+			Name:     "regular expression after a block",
+			Source:   "if (a) {}\n/ab  +c/.test(s)\nfunction f() { return 1 }\n/d  e/.test(s)",
+			Expected: "if(a){}/ab  +c/.test(s)\nfunction f(){return 1}/d  e/.test(s)",
+		},
+		{
+			Name:     "regular expression on the line after an object literal",
+			Source:   "x = {}\n/'/.test(s)",
+			Expected: "x={}/'/.test(s)",
+		},
+		{
+			Name:     "division after an object literal and a call",
+			Source:   "x = { a: 1 } / 2; y = f( a ) / 2 / b; z = (c) / d",
+			Expected: "x={a:1}/2;y=f(a)/2/b;z=(c)/d",
+		},
+		{
+			Name:     "template literals",
+			Source:   "const s = `a  ${ x + `b  ${ y }` }  c`",
+			Expected: "const s=`a  ${x+`b  ${y}`}  c`",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			out, err := minify.JS([]byte(tc.Source))
+			if err != nil {
+				t.Fatalf("JS failed: %v", err)
+			}
+			if string(out) != tc.Expected {
+				t.Errorf("Expected %q, but got %q", tc.Expected, out)
+			}
+		})
+	}
+
+	_, err := minify.JS([]byte("a = 1\nb = 'open\n"))
+	var minifyErr *minify.Error
+	if !errors.As(err, &minifyErr) || minifyErr.Line != 2 {
+		t.Errorf("Expected an error on line 2 for an unterminated string, but got %v", err)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Source   string
+		Expected string
+	}{
+		{
+			Name:     "document",
+			Source:   "<!DOCTYPE html>\n<html>\n  <head>\n    <title> A  page </title>\n  </head>\n  <body>\n    <!-- comment -->\n    <p>Some   <b>bold</b>\n    <i>text</i>.</p>\n  </body>\n</html>\n",
+			Expected: "<!DOCTYPE html><html><head><title>A page</title></head><body><p>Some <b>bold</b> <i>text</i>.</p></body></html>",
+		},
+		{
+			Name:     "tags",
+			Source:   "<a   href=\"a  b\"\n   class=x >link</a> <br />",
+			Expected: "<a href=\"a  b\" class=x>link</a> <br/>",
+		},
+		{
+			Name:     "preformatted",
+			Source:   "<pre>  a\n   b </pre>\n<textarea> c  d </textarea>",
+			Expected: "<pre>  a\n   b </pre><textarea> c  d </textarea>",
+		},
+		{
+			Name:     "styles and scripts",
+			Source:   "<style>\n a { color : red; }\n</style>\n<script>\n var x = 1 ;\n</script>\n<script type=\"application/ld+json\">{ \"a\" :  1 }</script>",
+			Expected: "<style>a{color:red}</style><script>var x=1;</script><script type=\"application/ld+json\">{ \"a\" :  1 }</script>",
+		},
+		{
+			Name:     "conditional comments",
+			Source:   "<!--[if IE]><p>old</p><![endif]-->",
+			Expected: "<!--[if IE]><p>old</p><![endif]-->",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if out := string(minify.HTML([]byte(tc.Source))); out != tc.Expected {
+				t.Errorf("Expected %q, but got %q", tc.Expected, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package lemur
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	texttemplate "text/template"
+	"time"
+)
+
+// WithDevMode enables development mode. The backing filesystem is polled for
+// changed modification times at most once per interval, checked lazily when a
+// template is rendered, and the _defaults base template and any affected layout
+// sets are rebuilt without restarting the process.
+//
+// A failed reload keeps serving the last good set of templates, the parse error
+// is available from ReloadErr until a later reload succeeds.
+func WithDevMode(interval time.Duration) Option {
+	return func(wh *Lemur) {
+		wh.reload = &reloader{interval: interval}
+	}
+}
+
+// Reload checks the backing filesystem for changes immediately and rebuilds
+// any affected layout sets. It returns the parse error if the rebuild fails, in
+// which case the last good set of templates stays in use.
+//
+// Reload is only available in development mode, see WithDevMode.
+func (wh *Lemur) Reload() error {
+	if wh.reload == nil {
+		return fmt.Errorf("lemur Reload: development mode is not enabled")
+	}
+
+	return wh.reload.check()
+}
+
+// ReloadErr returns the error from the most recent failed reload, or nil if the
+// most recent reload succeeded or development mode is not enabled.
+func (wh *Lemur) ReloadErr() error {
+	if wh.reload == nil {
+		return nil
+	}
+
+	wh.reload.mu.RLock()
+	defer wh.reload.mu.RUnlock()
+
+	return wh.reload.err
+}
+
+// layoutSet returns the named layout set, checking for template changes first
+// when development mode is enabled.
+func (wh *Lemur) layoutSet(name string) (*template.Template, bool) {
+	if wh.reload == nil {
+		tmpl, ok := wh.layouts[name]
+		return tmpl, ok
+	}
+
+	wh.reload.poll()
+
+	wh.reload.mu.RLock()
+	defer wh.reload.mu.RUnlock()
+
+	tmpl, ok := wh.reload.sets.layouts[name]
+	return tmpl, ok
+}
+
+// textSet returns the text set of the named layout, checking for template
+// changes first when development mode is enabled.
+func (wh *Lemur) textSet(name string) (*texttemplate.Template, bool) {
+	if wh.reload == nil {
+		tmpl, ok := wh.text[name]
+		return tmpl, ok
+	}
+
+	wh.reload.poll()
+
+	wh.reload.mu.RLock()
+	defer wh.reload.mu.RUnlock()
+
+	tmpl, ok := wh.reload.sets.text[name]
+	return tmpl, ok
+}
+
+// layoutSets returns the current map of layout sets. The returned map must not
+// be modified.
+func (wh *Lemur) layoutSets() map[string]*template.Template {
+	if wh.reload == nil {
+		return wh.layouts
+	}
+
+	wh.reload.poll()
+
+	wh.reload.mu.RLock()
+	defer wh.reload.mu.RUnlock()
+
+	return wh.reload.sets.layouts
+}
+
+// reloader holds the templates of a Lemur in development mode. The sets are
+// replaced, never modified, so a map read under mu may be used after it is
+// released while a concurrent reload swaps in a new one.
+type reloader struct {
+	interval time.Duration
+	fsys     fs.FS
+	funcs    template.FuncMap
+
+	// checking is set while a goroutine is comparing fingerprints, so that
+	// concurrent renders do not all walk the filesystem at once.
+	checking int32
+
+	mu        sync.RWMutex
+	sets      *templateSets
+	stamps    map[string]uint64
+	lastCheck time.Time
+	err       error
+}
+
+// start records the initially loaded templates and their fingerprints.
+func (r *reloader) start(fsys fs.FS, funcMap template.FuncMap, sets *templateSets) error {
+	stamps, err := fingerprintLayouts(fsys)
+	if err != nil {
+		return err
+	}
+
+	r.fsys = fsys
+	r.funcs = funcMap
+	r.sets = sets
+	r.stamps = stamps
+	r.lastCheck = time.Now()
+
+	return nil
+}
+
+// poll runs check if the polling interval has elapsed since the last one, and
+// no other goroutine is already checking.
+func (r *reloader) poll() {
+	r.mu.RLock()
+	due := time.Since(r.lastCheck) >= r.interval
+	r.mu.RUnlock()
+
+	if !due {
+		return
+	}
+
+	// Errors are recorded on the reloader and reported through ReloadErr.
+	_ = r.check()
+}
+
+// check compares the current fingerprints of the layout directories with the
+// recorded ones and rebuilds what changed. A change to _defaults, or a layout
+// being added or removed, rebuilds everything.
+func (r *reloader) check() error {
+	if !atomic.CompareAndSwapInt32(&r.checking, 0, 1) {
+		return r.currentErr()
+	}
+	defer atomic.StoreInt32(&r.checking, 0)
+
+	stamps, err := fingerprintLayouts(r.fsys)
+	if err != nil {
+		return r.finish(nil, nil, err)
+	}
+
+	r.mu.RLock()
+	oldStamps, old := r.stamps, r.sets
+	r.mu.RUnlock()
+
+	if stampsEqual(stamps, oldStamps) {
+		return r.finish(nil, nil, nil)
+	}
+
+	if stamps[DEFAULT_TEMPLATE] != oldStamps[DEFAULT_TEMPLATE] || !sameLayoutNames(stamps, oldStamps) {
+		sets, err := loadTemplates(r.fsys, r.funcs)
+		if err != nil {
+			return r.finish(nil, nil, err)
+		}
+		return r.finish(stamps, sets, nil)
+	}
+
+	sets := &templateSets{
+		base:     old.base,
+		layouts:  make(map[string]*template.Template, len(old.layouts)),
+		textBase: old.textBase,
+		text:     make(map[string]*texttemplate.Template, len(old.text)),
+	}
+	for name, tmpl := range old.layouts {
+		if stamps[name] == oldStamps[name] {
+			sets.layouts[name] = tmpl
+			sets.text[name] = old.text[name]
+			continue
+		}
+
+		tmpl, err := processLayoutDirectory(r.fsys, old.base, LAYOUTS_DIR_PATH, name)
+		if err != nil {
+			return r.finish(nil, nil, err)
+		}
+		text, err := processTextLayoutDirectory(r.fsys, old.textBase, LAYOUTS_DIR_PATH, name)
+		if err != nil {
+			return r.finish(nil, nil, err)
+		}
+		sets.layouts[name] = tmpl
+		sets.text[name] = text
+	}
+
+	return r.finish(stamps, sets, nil)
+}
+
+// finish records the outcome of a check. New templates are swapped in only
+// when sets is non-nil.
+func (r *reloader) finish(stamps map[string]uint64, sets *templateSets, err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastCheck = time.Now()
+	if err != nil {
+		r.err = fmt.Errorf("lemur Reload: %w", err)
+		return r.err
+	}
+
+	if sets != nil {
+		r.stamps = stamps
+		r.sets = sets
+	}
+	r.err = nil
+
+	return nil
+}
+
+func (r *reloader) currentErr() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.err
+}
+
+// fingerprintLayouts returns a hash of the names, sizes and modification times
+// of every file below each layout directory, keyed by layout name.
+func fingerprintLayouts(fsys fs.FS) (map[string]uint64, error) {
+	entries, err := fs.ReadDir(fsys, LAYOUTS_DIR_PATH)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading %s from filesystem: %s", ErrTemplateDir, LAYOUTS_DIR_PATH, err)
+	}
+
+	stamps := make(map[string]uint64, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || !isLayoutDir(name) {
+			continue
+		}
+
+		h := fnv.New64a()
+		err := fs.WalkDir(fsys, filepath.Join(LAYOUTS_DIR_PATH, name), func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\x00%d\x00%d\x00", p, info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error fingerprinting template set %s: %w", name, err)
+		}
+
+		stamps[name] = h.Sum64()
+	}
+
+	return stamps, nil
+}
+
+func stampsEqual(a, b map[string]uint64) bool {
+	if !sameLayoutNames(a, b) {
+		return false
+	}
+	for name, stamp := range a {
+		if b[name] != stamp {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sameLayoutNames(a, b map[string]uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name := range a {
+		if _, ok := b[name]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,155 @@
+package lemur_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ukiahsmith/lemur"
+)
+
+// writeTemplate writes content to the named file below dir, moving its
+// modification time forward so that the change is seen even on filesystems
+// with a coarse timestamp resolution.
+func writeTemplate(t *testing.T, dir string, name string, content string, age time.Duration) {
+	t.Helper()
+
+	p := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatalf("writeTemplate: %s", err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatalf("writeTemplate: %s", err)
+	}
+	mtime := time.Now().Add(age)
+	if err := os.Chtimes(p, mtime, mtime); err != nil {
+		t.Fatalf("writeTemplate: %s", err)
+	}
+}
+
+func TestLemur_DevModeReload(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "layouts/_defaults/_index.html.tmpl", `v1 {{ block "main.html.tmpl" . }}{{ end }}`, -time.Hour)
+	writeTemplate(t, dir, "layouts/shop/main.html.tmpl", "shop v1", -time.Hour)
+	writeTemplate(t, dir, "layouts/blog/main.html.tmpl", "blog v1", -time.Hour)
+
+	wh, err := lemur.New(os.DirFS(dir), nil, lemur.WithDevMode(0))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	expectRender := func(layout string, expected string) {
+		t.Helper()
+		out, err := wh.Srender(layout, nil)
+		if err != nil {
+			t.Fatalf("Srender(%q) failed: %v", layout, err)
+		}
+		if out != expected {
+			t.Errorf("Srender(%q): expected %q, but got %q", layout, expected, out)
+		}
+	}
+
+	expectRender("shop", "v1 shop v1")
+
+	// A change to a single layout rebuilds that layout.
+	writeTemplate(t, dir, "layouts/shop/main.html.tmpl", "shop v2", 0)
+	expectRender("shop", "v1 shop v2")
+	expectRender("blog", "v1 blog v1")
+
+	// A change to _defaults rebuilds every layout.
+	writeTemplate(t, dir, "layouts/_defaults/_index.html.tmpl", `v2 {{ block "main.html.tmpl" . }}{{ end }}`, time.Minute)
+	expectRender("blog", "v2 blog v1")
+
+	// A new layout becomes available.
+	writeTemplate(t, dir, "layouts/cart/main.html.tmpl", "cart v1", 0)
+	expectRender("cart", "v2 cart v1")
+
+	// A broken template keeps the last good set and reports the error.
+	writeTemplate(t, dir, "layouts/shop/main.html.tmpl", "shop {{ .Broken ", 2*time.Minute)
+	if err := wh.Reload(); err == nil {
+		t.Fatalf("Expected Reload to fail on a broken template, but got nil")
+	}
+	expectRender("shop", "v2 shop v2")
+	if wh.ReloadErr() == nil {
+		t.Errorf("Expected ReloadErr to report the failed reload, but got nil")
+	}
+
+	// Fixing the template clears the error.
+	writeTemplate(t, dir, "layouts/shop/main.html.tmpl", "shop v3", 3*time.Minute)
+	expectRender("shop", "v2 shop v3")
+	if err := wh.ReloadErr(); err != nil {
+		t.Errorf("Expected ReloadErr to be cleared, but got %v", err)
+	}
+}
+
+func TestLemur_DevModeReloadText(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "layouts/_defaults/_index.html.tmpl", "html", -time.Hour)
+	writeTemplate(t, dir, "layouts/_defaults/_index.txt.tmpl", `{{ block "body.txt.tmpl" . }}{{ end }}`, -time.Hour)
+	writeTemplate(t, dir, "layouts/order/body.txt.tmpl", "order v1", -time.Hour)
+
+	wh, err := lemur.New(os.DirFS(dir), nil, lemur.WithDevMode(0))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	writeTemplate(t, dir, "layouts/order/body.txt.tmpl", "order v2", 0)
+
+	out, err := wh.SrenderText("order", nil)
+	if err != nil {
+		t.Fatalf("SrenderText failed: %v", err)
+	}
+	if expected := "order v2"; out != expected {
+		t.Errorf("Expected %q, but got %q", expected, out)
+	}
+}
+
+func TestLemur_DevModeConcurrentRender(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "layouts/_defaults/_index.html.tmpl", `{{ block "main.html.tmpl" . }}{{ end }}`, -time.Hour)
+	writeTemplate(t, dir, "layouts/shop/main.html.tmpl", "shop", -time.Hour)
+
+	wh, err := lemur.New(os.DirFS(dir), nil, lemur.WithDevMode(0))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := wh.Srender("shop", nil); err != nil {
+					t.Errorf("Srender failed during reload: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 10; i++ {
+		writeTemplate(t, dir, "layouts/shop/main.html.tmpl", "shop", time.Duration(i)*time.Second)
+		if err := wh.Reload(); err != nil {
+			t.Errorf("Reload failed: %v", err)
+		}
+	}
+
+	wg.Wait()
+}
+
+func TestLemur_ReloadWithoutDevMode(t *testing.T) {
+	wh, err := lemur.New(os.DirFS("testdata/minimal"), nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	if err := wh.Reload(); err == nil {
+		t.Errorf("Expected Reload to fail without development mode, but got nil")
+	}
+	if err := wh.ReloadErr(); err != nil {
+		t.Errorf("Expected no ReloadErr without development mode, but got %v", err)
+	}
+}
@@ -1,9 +1,15 @@
 package lemur
 
 import (
+	"context"
 	"fmt"
+	"html/template"
 	"io"
+	"sort"
 	"strings"
+	texttemplate "text/template"
+
+	"github.com/ukiahsmith/lemur/minify"
 )
 
 // Srender renders the specified template by name with the given data and returns
@@ -16,14 +22,7 @@ import (
 // possible, using Render directly might be more efficient as it avoids the
 // intermediate string allocation.
 func (wh *Lemur) Srender(tmplName string, data interface{}) (string, error) {
-	var buf strings.Builder
-
-	err := wh.Render(&buf, tmplName, data)
-	if err != nil {
-		return "", err
-	}
-
-	return buf.String(), nil
+	return wh.SrenderTemplate(tmplName, DEFAULT_TEMPLATE_INDEX, data)
 }
 
 // Render executes the specified template by name, writing the output to the
@@ -33,23 +32,192 @@ func (wh *Lemur) Srender(tmplName string, data interface{}) (string, error) {
 // If tmplName is an empty string, it defaults to "_defaults".
 // The method will then execute the "_index.html.tmpl" template within that layout set.
 //
-// data is the data to be passed to the template for rendering.
+// data is the data to be passed to the template for rendering. When a Site is
+// configured with WithSite it is merged into data as .Site, see RenderPage.
 //
 // This is the primary method for rendering templates when you have an output
 // stream, such as an http.ResponseWriter or a file.
 func (wh *Lemur) Render(w io.Writer, tmplName string, data interface{}) error {
-	if tmplName == "" {
-		tmplName = "_defaults"
+	return wh.RenderTemplate(w, tmplName, DEFAULT_TEMPLATE_INDEX, data)
+}
+
+// SrenderTemplate renders the entry template within the named layout set and
+// returns the output as a string.
+//
+// It is the string returning counterpart of RenderTemplate, in the same way
+// that Srender is for Render.
+func (wh *Lemur) SrenderTemplate(layout string, entry string, data interface{}) (string, error) {
+	var buf strings.Builder
+
+	// The output is already discarded on error, so there is no need to buffer
+	// it twice in atomic mode.
+	err := wh.renderTemplate(context.Background(), &buf, layout, entry, data, false)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// RenderTemplate executes the entry template within the named layout set,
+// writing the output to the provided io.Writer.
+//
+// layout is resolved the same way as the tmplName argument of Render, an empty
+// string selects "_defaults", and aliases and fallbacks apply, see Resolve.
+// entry may name any template defined in the layout
+// set, including those inherited from _defaults, for example "author.html.tmpl"
+// to render a single partial for an htmx fragment swap.
+//
+// A returned error is a *RenderError, matching ErrUnknownLayout,
+// ErrUnknownTemplate or ErrExec with errors.Is.
+//
+// In atomic mode, see WithAtomicRender, nothing is written to w unless the
+// template executes successfully.
+func (wh *Lemur) RenderTemplate(w io.Writer, layout string, entry string, data interface{}) error {
+	return wh.renderTemplate(context.Background(), w, layout, entry, data, wh.atomic)
+}
+
+// RenderContext is Render with a context. Rendering stops with the context's
+// error once ctx is done, and funcs registered with WithContextFuncs receive
+// ctx.
+func (wh *Lemur) RenderContext(ctx context.Context, w io.Writer, layout string, data interface{}) error {
+	return wh.renderTemplate(ctx, w, layout, DEFAULT_TEMPLATE_INDEX, data, wh.atomic)
+}
+
+// RenderTemplateContext is RenderTemplate with a context, see RenderContext.
+func (wh *Lemur) RenderTemplateContext(ctx context.Context, w io.Writer, layout string, entry string, data interface{}) error {
+	return wh.renderTemplate(ctx, w, layout, entry, data, wh.atomic)
+}
+
+// executor is the part of html/template and text/template a render uses.
+type executor interface {
+	ExecuteTemplate(w io.Writer, name string, data interface{}) error
+}
+
+// bindExecutor binds the context funcs to ctx for either kind of template set,
+// see bindContext.
+func (wh *Lemur) bindExecutor(ctx context.Context, exec executor) (executor, error) {
+	switch tmpl := exec.(type) {
+	case *texttemplate.Template:
+		return wh.bindTextContext(ctx, tmpl)
+	case *template.Template:
+		return wh.bindContext(ctx, tmpl)
 	}
 
-	if tmpl, ok := wh.layouts[tmplName]; ok {
-		err := tmpl.ExecuteTemplate(w, "_index.html.tmpl", data)
+	return exec, nil
+}
+
+// renderTemplate implements RenderTemplate. When atomic is true, or the output
+// is minified, it is executed into a pooled buffer and only copied to w on
+// success.
+func (wh *Lemur) renderTemplate(ctx context.Context, w io.Writer, layout string, entry string, data interface{}, atomic bool) error {
+	if layout == "" {
+		layout = DEFAULT_TEMPLATE
+	}
+
+	resolved, tmpl, ok := wh.resolveLayout(layout)
+	if !ok {
+		return &RenderError{Kind: ErrUnknownLayout, Layout: layout, Entry: entry}
+	}
+
+	var exec executor = tmpl
+	if isTextTemplate(entry) {
+		text, ok := wh.textSet(resolved)
+		if !ok || text.Lookup(entry) == nil {
+			return &RenderError{Kind: ErrUnknownTemplate, Layout: layout, Resolved: resolved, Entry: entry}
+		}
+		exec = text
+	} else if tmpl.Lookup(entry) == nil {
+		return &RenderError{Kind: ErrUnknownTemplate, Layout: layout, Resolved: resolved, Entry: entry}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return &RenderError{Kind: ErrExec, Layout: layout, Resolved: resolved, Entry: entry, Err: err}
+	}
+
+	exec, err := wh.bindExecutor(ctx, exec)
+	if err != nil {
+		return &RenderError{Kind: ErrExec, Layout: layout, Resolved: resolved, Entry: entry, Err: err}
+	}
+
+	data = wh.withSite(data)
+
+	minifyHTML := wh.minify && !isTextTemplate(entry)
+	if !atomic && !minifyHTML {
+		err := exec.ExecuteTemplate(contextWriter(ctx, w), entry, data)
 		if err != nil {
-			return fmt.Errorf("lemur Render: could not render template: %w", err)
+			return newExecError(layout, resolved, entry, err)
 		}
-	} else {
-		return fmt.Errorf("lemur Render: no template with name %q", tmplName)
+		return nil
+	}
+
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+
+	err = exec.ExecuteTemplate(contextWriter(ctx, buf), entry, data)
+	if err != nil {
+		return newExecError(layout, resolved, entry, err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return &RenderError{Kind: ErrExec, Layout: layout, Resolved: resolved, Entry: entry, Err: err}
+	}
+
+	out := buf.Bytes()
+	if minifyHTML {
+		out = minify.HTML(out)
+	}
+	if _, err := w.Write(out); err != nil {
+		return fmt.Errorf("lemur Render: could not write output: %w", err)
 	}
 
 	return nil
 }
+
+// Layouts returns the sorted names of every layout set known to the Lemur,
+// including "_defaults".
+func (wh *Lemur) Layouts() []string {
+	layouts := wh.layoutSets()
+
+	names := make([]string, 0, len(layouts))
+	for name := range layouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Templates returns the sorted names of the entry templates that can be passed
+// to RenderTemplate for the named layout set, including its *.txt.tmpl text
+// templates. An empty layout selects "_defaults".
+func (wh *Lemur) Templates(layout string) ([]string, error) {
+	if layout == "" {
+		layout = DEFAULT_TEMPLATE
+	}
+
+	tmpl, ok := wh.layoutSet(layout)
+	if !ok {
+		return nil, fmt.Errorf("lemur Templates: no template with name %q", layout)
+	}
+
+	var names []string
+	for _, t := range tmpl.Templates() {
+		// Skip the unparsed root of the template set.
+		if t.Tree == nil {
+			continue
+		}
+		names = append(names, t.Name())
+	}
+	if text, ok := wh.textSet(layout); ok {
+		for _, t := range text.Templates() {
+			if t.Tree == nil {
+				continue
+			}
+			names = append(names, t.Name())
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
@@ -1,12 +1,17 @@
 package lemur_test
 
 import (
+	"errors"
+	"html/template"
+	"io"
 	"io/fs"
 	"os"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/ukiahsmith/lemur"
+	"github.com/ukiahsmith/lemur/funcs"
 )
 
 func TestLemur_Render(t *testing.T) {
@@ -144,3 +149,336 @@ func TestLemur_Srender_Errors(t *testing.T) {
 		})
 	}
 }
+
+func TestLemur_RenderTemplate(t *testing.T) {
+	type testCase struct {
+		Name           string
+		TemplateFS     fs.FS
+		Layout         string
+		Entry          string
+		Data           interface{}
+		ExpectedOutput string
+		ErrorContains  string // Substring to check in error message, empty for success
+	}
+
+	testCases := []testCase{
+		{
+			Name:           "Partial inherited from _defaults",
+			TemplateFS:     os.DirFS("testdata/full_dir"),
+			Layout:         "other_tmpl_2",
+			Entry:          "author.html.tmpl",
+			ExpectedOutput: "Author: Name Name\n",
+		},
+		{
+			Name:           "Partial defined in layout",
+			TemplateFS:     os.DirFS("testdata/full_dir"),
+			Layout:         "other_tmpl",
+			Entry:          "atmpl.html.tmpl",
+			ExpectedOutput: "full_dir atmpl.html.tmpl\n",
+		},
+		{
+			Name:           "Partial in a layout subdirectory",
+			TemplateFS:     os.DirFS("testdata/nested"),
+			Layout:         "mytemplate",
+			Entry:          "partials/cards/card.html.tmpl",
+			ExpectedOutput: "nested mytemplate partials/cards/card.html.tmpl\n",
+		},
+		{
+			Name:           "Empty layout name",
+			TemplateFS:     os.DirFS("testdata/full_dir"),
+			Layout:         "", // Should default to _defaults
+			Entry:          "author.html.tmpl",
+			ExpectedOutput: "Author: Name Name\n",
+		},
+		{
+			Name:          "Non-existent layout",
+			TemplateFS:    os.DirFS("testdata/full_dir"),
+			Layout:        "nonexistent",
+			Entry:         "author.html.tmpl",
+			ErrorContains: `lemur Render: no template with name "nonexistent"`,
+		},
+		{
+			Name:          "Non-existent entry",
+			TemplateFS:    os.DirFS("testdata/full_dir"),
+			Layout:        "mytemplate",
+			Entry:         "atmpl.html.tmpl",
+			ErrorContains: `lemur Render: no entry template "atmpl.html.tmpl" in layout "mytemplate"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			wh, err := lemur.New(tc.TemplateFS, nil)
+			if err != nil {
+				t.Fatalf("For test case %q, lemur.New failed during setup: %v", tc.Name, err)
+			}
+
+			out, err := wh.SrenderTemplate(tc.Layout, tc.Entry, tc.Data)
+
+			if tc.ErrorContains != "" {
+				if err == nil {
+					t.Fatalf("Expected an error, but got nil")
+				}
+				if !strings.Contains(err.Error(), tc.ErrorContains) {
+					t.Errorf("Expected error message to contain %q, but got %q", tc.ErrorContains, err.Error())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if out != tc.ExpectedOutput {
+				t.Errorf("Expected output %q, but got %q", tc.ExpectedOutput, out)
+			}
+		})
+	}
+}
+
+func TestLemur_Templates(t *testing.T) {
+	wh, err := lemur.New(os.DirFS("testdata/full_dir"), nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	expectedLayouts := "_defaults,mytemplate,other_tmpl,other_tmpl_2"
+	if got := strings.Join(wh.Layouts(), ","); got != expectedLayouts {
+		t.Errorf("Expected layouts %q, but got %q", expectedLayouts, got)
+	}
+
+	names, err := wh.Templates("other_tmpl_2")
+	if err != nil {
+		t.Fatalf("Templates failed: %v", err)
+	}
+
+	expectedNames := "_index.html,_index.html.tmpl,atmpl.html,atmpl.html.tmpl,author.html,author.html.tmpl"
+	if got := strings.Join(names, ","); got != expectedNames {
+		t.Errorf("Expected templates %q, but got %q", expectedNames, got)
+	}
+
+	if _, err := wh.Templates("nonexistent"); err == nil {
+		t.Errorf("Expected an error for a non-existent layout, but got nil")
+	}
+}
+
+func TestLemur_WithMarkdown(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ markdown . }}`)},
+	}
+
+	wh, err := lemur.New(templateFS, nil, lemur.WithMarkdown(funcs.NewMarkdownRenderer(funcs.MarkdownGFM())))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	out, err := wh.Srender("", "~~gone~~ <i>raw</i>")
+	if err != nil {
+		t.Fatalf("Srender failed: %v", err)
+	}
+
+	expected := "<p><del>gone</del> <!-- raw HTML omitted -->raw<!-- raw HTML omitted --></p>\n"
+	if out != expected {
+		t.Errorf("Expected output %q, but got %q", expected, out)
+	}
+}
+
+func TestLemur_WithSanitizer(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ sanitizeHTML . }}`)},
+	}
+
+	sanitizer := funcs.NewSanitizer(funcs.SanitizePolicy{Tags: map[string][]string{"em": nil}})
+	wh, err := lemur.New(templateFS, nil, lemur.WithSanitizer(sanitizer))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	out, err := wh.Srender("", "<p><em>kept</em></p>")
+	if err != nil {
+		t.Fatalf("Srender failed: %v", err)
+	}
+
+	expected := "<em>kept</em>"
+	if out != expected {
+		t.Errorf("Expected output %q, but got %q", expected, out)
+	}
+}
+
+func TestLemur_UserFuncsOverrideOptions(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ markdown . }} {{ sanitizeHTML . }}`)},
+	}
+
+	userFuncs := template.FuncMap{
+		"markdown":     func(s string) string { return "user markdown" },
+		"sanitizeHTML": func(s string) string { return "user sanitizeHTML" },
+	}
+	sanitizer := funcs.NewSanitizer(funcs.SanitizePolicy{})
+	wh, err := lemur.New(templateFS, userFuncs, lemur.WithMarkdown(funcs.NewMarkdownRenderer()), lemur.WithSanitizer(sanitizer))
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	out, err := wh.Srender("", "*text*")
+	if err != nil {
+		t.Fatalf("Srender failed: %v", err)
+	}
+
+	expected := "user markdown user sanitizeHTML"
+	if out != expected {
+		t.Errorf("Expected output %q, but got %q", expected, out)
+	}
+}
+
+func TestLemur_RenderError(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl":        &fstest.MapFile{Data: []byte("index\n{{ template \"partials/item.html.tmpl\" . }}")},
+		"layouts/_defaults/partials/item.html.tmpl": &fstest.MapFile{Data: []byte("item\n\n  {{ index .Items 5 }}")},
+	}
+
+	wh, err := lemur.New(templateFS, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	type testCase struct {
+		Name     string
+		Layout   string
+		Entry    string
+		Kind     error
+		Template string
+		Line     int
+		Col      int
+	}
+
+	testCases := []testCase{
+		{Name: "Unknown layout", Layout: "nonexistent", Entry: "_index.html.tmpl", Kind: lemur.ErrUnknownLayout},
+		{Name: "Unknown template", Layout: "_defaults", Entry: "nonexistent.html.tmpl", Kind: lemur.ErrUnknownTemplate},
+		{Name: "Exec error in partial", Layout: "_defaults", Entry: "_index.html.tmpl", Kind: lemur.ErrExec, Template: "partials/item.html.tmpl", Line: 3, Col: 5},
+	}
+
+	kinds := []error{lemur.ErrUnknownLayout, lemur.ErrUnknownTemplate, lemur.ErrExec}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := wh.RenderTemplate(io.Discard, tc.Layout, tc.Entry, map[string][]int{"Items": {1}})
+			if err == nil {
+				t.Fatalf("Expected an error, but got nil")
+			}
+
+			for _, kind := range kinds {
+				want := kind == tc.Kind
+				if got := errors.Is(err, kind); got != want {
+					t.Errorf("Expected errors.Is(err, %v) to be %t, got %t", kind, want, got)
+				}
+			}
+
+			var renderErr *lemur.RenderError
+			if !errors.As(err, &renderErr) {
+				t.Fatalf("Expected error to be a *lemur.RenderError, got %T", err)
+			}
+
+			if renderErr.Layout != tc.Layout || renderErr.Entry != tc.Entry {
+				t.Errorf("Expected layout %q and entry %q, got %q and %q", tc.Layout, tc.Entry, renderErr.Layout, renderErr.Entry)
+			}
+			if renderErr.Template != tc.Template || renderErr.Line != tc.Line || renderErr.Col != tc.Col {
+				t.Errorf("Expected failure at %s:%d:%d, got %s:%d:%d", tc.Template, tc.Line, tc.Col, renderErr.Template, renderErr.Line, renderErr.Col)
+			}
+		})
+	}
+}
+
+func TestLemur_WithAtomicRender(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`<h1>partial page</h1>{{ mod .Num .Denom }}`)},
+	}
+
+	testCases := []struct {
+		Name     string
+		Options  []lemur.Option
+		Data     map[string]int
+		Expected string
+		Err      bool
+	}{
+		{"Streaming writes partial output", nil, map[string]int{"Num": 1, "Denom": 0}, "<h1>partial page</h1>", true},
+		{"Atomic writes nothing on error", []lemur.Option{lemur.WithAtomicRender()}, map[string]int{"Num": 1, "Denom": 0}, "", true},
+		{"Atomic writes output on success", []lemur.Option{lemur.WithAtomicRender()}, map[string]int{"Num": 5, "Denom": 3}, "<h1>partial page</h1>2", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			wh, err := lemur.New(templateFS, nil, tc.Options...)
+			if err != nil {
+				t.Fatalf("lemur.New failed during setup: %v", err)
+			}
+
+			var buf strings.Builder
+			err = wh.Render(&buf, "", tc.Data)
+			if (err != nil) != tc.Err {
+				t.Errorf("Expected error %t, but got %v", tc.Err, err)
+			}
+			if buf.String() != tc.Expected {
+				t.Errorf("Expected output %q, but got %q", tc.Expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestLemur_WithMinifyHTML(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte("<div>\n  <!-- note -->\n  <p>Hello,   {{ . }}</p>\n</div>\n")},
+		"layouts/_defaults/_index.txt.tmpl":  &fstest.MapFile{Data: []byte("Hello,   {{ . }}\n")},
+	}
+
+	testCases := []struct {
+		Name     string
+		Options  []lemur.Option
+		Entry    string
+		Expected string
+	}{
+		{"Not minified by default", nil, "_index.html.tmpl", "<div>\n  \n  <p>Hello,   lemur</p>\n</div>\n"},
+		{"HTML is minified", []lemur.Option{lemur.WithMinifyHTML()}, "_index.html.tmpl", "<div><p>Hello, lemur</p></div>"},
+		{"Text is not minified", []lemur.Option{lemur.WithMinifyHTML()}, "_index.txt.tmpl", "Hello,   lemur\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			wh, err := lemur.New(templateFS, nil, tc.Options...)
+			if err != nil {
+				t.Fatalf("lemur.New failed during setup: %v", err)
+			}
+
+			var buf strings.Builder
+			if err := wh.RenderTemplate(&buf, "", tc.Entry, "lemur"); err != nil {
+				t.Fatalf("RenderTemplate failed: %v", err)
+			}
+			if buf.String() != tc.Expected {
+				t.Errorf("Expected output %q, but got %q", tc.Expected, buf.String())
+			}
+		})
+	}
+}
+
+func benchmarkRender(b *testing.B, opts ...lemur.Option) {
+	wh, err := lemur.New(os.DirFS("testdata/full_dir"), nil, opts...)
+	if err != nil {
+		b.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := wh.Render(io.Discard, "other_tmpl_2", nil); err != nil {
+			b.Fatalf("Render failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkRender_Streaming(b *testing.B) {
+	benchmarkRender(b)
+}
+
+func BenchmarkRender_Atomic(b *testing.B) {
+	benchmarkRender(b, lemur.WithAtomicRender())
+}
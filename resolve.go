@@ -0,0 +1,78 @@
+package lemur
+
+import (
+	"html/template"
+	"strings"
+)
+
+// WithLayoutFallback makes a missing layout fall back to its parent. A layout
+// name is split on '/', so "product/featured" falls back to "product", and a
+// name without a parent falls back to "_defaults".
+func WithLayoutFallback() Option {
+	return func(wh *Lemur) {
+		wh.fallback = true
+	}
+}
+
+// WithLayoutAliases adds aliases from a requested layout name to the layout
+// that renders it, e.g. "shop" to "listing". An alias applies whether or not a
+// layout with the alias name exists, and its target may itself be an alias or
+// fall back, see WithLayoutFallback.
+func WithLayoutAliases(aliases map[string]string) Option {
+	return func(wh *Lemur) {
+		if wh.aliases == nil {
+			wh.aliases = make(map[string]string, len(aliases))
+		}
+		for from, to := range aliases {
+			wh.aliases[from] = to
+		}
+	}
+}
+
+// Resolve returns the name of the layout set that rendering layout would use,
+// after applying aliases and fallbacks, for example to log which layout served
+// a request. An empty layout resolves to "_defaults". If no layout set
+// matches, the error is a *RenderError matching ErrUnknownLayout.
+func (wh *Lemur) Resolve(layout string) (string, error) {
+	if layout == "" {
+		layout = DEFAULT_TEMPLATE
+	}
+
+	resolved, _, ok := wh.resolveLayout(layout)
+	if !ok {
+		return "", &RenderError{Kind: ErrUnknownLayout, Layout: layout}
+	}
+
+	return resolved, nil
+}
+
+// resolveLayout follows the aliases and fallbacks from name to an existing
+// layout set, returning its name and template.
+func (wh *Lemur) resolveLayout(name string) (string, *template.Template, bool) {
+	layouts := wh.layoutSets()
+	seen := make(map[string]bool)
+
+	for {
+		if to, ok := wh.aliases[name]; ok && !seen[name] {
+			// seen guards against alias cycles, a name already followed is
+			// looked up as itself.
+			seen[name] = true
+			name = to
+			continue
+		}
+
+		if tmpl, ok := layouts[name]; ok {
+			return name, tmpl, true
+		}
+
+		if !wh.fallback || name == DEFAULT_TEMPLATE {
+			return "", nil, false
+		}
+
+		if i := strings.LastIndex(name, "/"); i > 0 {
+			name = name[:i]
+		} else {
+			name = DEFAULT_TEMPLATE
+		}
+	}
+}
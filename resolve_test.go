@@ -0,0 +1,101 @@
+package lemur_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ukiahsmith/lemur"
+)
+
+func TestLemur_Resolve(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ block "main.html.tmpl" . }}defaults{{ end }}`)},
+		"layouts/product/main.html.tmpl":     &fstest.MapFile{Data: []byte(`product`)},
+		"layouts/listing/main.html.tmpl":     &fstest.MapFile{Data: []byte(`listing`)},
+	}
+
+	aliases := map[string]string{
+		"shop":  "listing",
+		"store": "shop",
+		"loopA": "loopB",
+		"loopB": "loopA",
+	}
+
+	testCases := []struct {
+		Name     string
+		Options  []lemur.Option
+		Layout   string
+		Resolved string // empty when the layout should not resolve
+		Output   string
+	}{
+		{"Exact", nil, "product", "product", "product"},
+		{"Empty is defaults", nil, "", "_defaults", "defaults"},
+		{"No fallback by default", nil, "product/featured", "", ""},
+		{"Fallback to parent", []lemur.Option{lemur.WithLayoutFallback()}, "product/featured", "product", "product"},
+		{"Fallback through several parents", []lemur.Option{lemur.WithLayoutFallback()}, "product/featured/summer", "product", "product"},
+		{"Fallback to defaults", []lemur.Option{lemur.WithLayoutFallback()}, "blog/post", "_defaults", "defaults"},
+		{"Alias", []lemur.Option{lemur.WithLayoutAliases(aliases)}, "shop", "listing", "listing"},
+		{"Alias chain", []lemur.Option{lemur.WithLayoutAliases(aliases)}, "store", "listing", "listing"},
+		{"Alias cycle", []lemur.Option{lemur.WithLayoutAliases(aliases)}, "loopA", "", ""},
+		{"Alias cycle with fallback", []lemur.Option{lemur.WithLayoutAliases(aliases), lemur.WithLayoutFallback()}, "loopA", "_defaults", "defaults"},
+		{"Alias then fallback", []lemur.Option{lemur.WithLayoutAliases(map[string]string{"featured": "product/featured"}), lemur.WithLayoutFallback()}, "featured", "product", "product"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			wh, err := lemur.New(templateFS, nil, tc.Options...)
+			if err != nil {
+				t.Fatalf("lemur.New failed during setup: %v", err)
+			}
+
+			resolved, resolveErr := wh.Resolve(tc.Layout)
+			out, renderErr := wh.Srender(tc.Layout, nil)
+
+			if tc.Resolved == "" {
+				if !errors.Is(resolveErr, lemur.ErrUnknownLayout) {
+					t.Errorf("Expected Resolve to fail with %v, but got %v", lemur.ErrUnknownLayout, resolveErr)
+				}
+				if !errors.Is(renderErr, lemur.ErrUnknownLayout) {
+					t.Errorf("Expected Srender to fail with %v, but got %v", lemur.ErrUnknownLayout, renderErr)
+				}
+				return
+			}
+
+			if resolveErr != nil {
+				t.Fatalf("Resolve failed: %v", resolveErr)
+			}
+			if resolved != tc.Resolved {
+				t.Errorf("Expected %q to resolve to %q, but got %q", tc.Layout, tc.Resolved, resolved)
+			}
+
+			if renderErr != nil {
+				t.Fatalf("Srender failed: %v", renderErr)
+			}
+			if out != tc.Output {
+				t.Errorf("Expected output %q, but got %q", tc.Output, out)
+			}
+		})
+	}
+}
+
+func TestLemur_RenderError_Resolved(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`{{ mod 1 0 }}`)},
+	}
+
+	wh, err := lemur.New(templateFS, nil, lemur.WithLayoutFallback())
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	_, err = wh.Srender("product/featured", nil)
+
+	var renderErr *lemur.RenderError
+	if !errors.As(err, &renderErr) {
+		t.Fatalf("Expected a *lemur.RenderError, but got %v", err)
+	}
+	if renderErr.Layout != "product/featured" || renderErr.Resolved != "_defaults" {
+		t.Errorf("Expected layout %q resolved to %q, but got %q and %q", "product/featured", "_defaults", renderErr.Layout, renderErr.Resolved)
+	}
+}
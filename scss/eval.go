@@ -0,0 +1,795 @@
+package scss
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// scope holds the variables of a block, falling back to its parent.
+type scope struct {
+	vars   map[string]value
+	parent *scope
+}
+
+func (s *scope) get(name string) (value, bool) {
+	name = normalizeName(name)
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+func (s *scope) child() *scope {
+	return &scope{vars: make(map[string]value), parent: s}
+}
+
+// normalizeName makes "-" and "_" the same in variable and mixin names, as in
+// Sass.
+func normalizeName(name string) string {
+	return strings.Replace(name, "_", "-", -1)
+}
+
+type mixin struct {
+	file   string
+	params []param
+	body   []node
+}
+
+type param struct {
+	name string
+	def  string // default value expression, "" when the parameter is required
+}
+
+// content is the block passed to a mixin by @include, evaluated by @content
+// with the variables of the including block.
+type content struct {
+	file  string
+	nodes []node
+	scope *scope
+	outer *content
+}
+
+// context is the state a block is evaluated in.
+type context struct {
+	file      string
+	selectors []string // selectors of the enclosing rule, nil at the root
+	wrappers  []string // enclosing at-rules, e.g. "@media (min-width: 40em)"
+	item      *item    // output item collecting declarations, nil when none
+	scope     *scope
+	content   *content
+	noNest    bool // nested rules do not join the enclosing selectors, as in @keyframes
+}
+
+// item is a rule or raw text of the output.
+type item struct {
+	wrappers  []string
+	selectors []string
+	decls     []string
+	raw       string
+}
+
+type compiler struct {
+	fsys   fs.FS
+	files  []string
+	read   map[string]bool
+	stack  []string
+	mixins map[string]*mixin
+	global *scope
+	out    []*item
+}
+
+// importFile parses and evaluates the file at name in c.
+func (c *compiler) importFile(name string, ctx context) error {
+	for _, f := range c.stack {
+		if f == name {
+			return fmt.Errorf("import cycle: %s", strings.Join(append(c.stack, name), " -> "))
+		}
+	}
+
+	src, err := fs.ReadFile(c.fsys, name)
+	if err != nil {
+		return err
+	}
+	if !c.read[name] {
+		c.read[name] = true
+		c.files = append(c.files, name)
+	}
+
+	nodes, err := parse(name, string(src))
+	if err != nil {
+		return err
+	}
+
+	c.stack = append(c.stack, name)
+	defer func() { c.stack = c.stack[:len(c.stack)-1] }()
+
+	ctx.file = name
+	return c.eval(nodes, ctx)
+}
+
+func (c *compiler) eval(nodes []node, ctx context) error {
+	for _, n := range nodes {
+		if err := c.evalNode(n, ctx); err != nil {
+			var e *Error
+			if errors.As(err, &e) {
+				return err
+			}
+			return &Error{File: ctx.file, Line: n.position(), Err: err}
+		}
+	}
+
+	return nil
+}
+
+func (c *compiler) evalNode(n node, ctx context) error {
+	switch n := n.(type) {
+	case *varNode:
+		return c.evalVar(n, ctx)
+	case *declNode:
+		return c.evalDecl(n, ctx)
+	case *ruleNode:
+		return c.evalRule(n, ctx)
+	case *atNode:
+		return c.evalAtRule(n, ctx)
+	}
+
+	return fmt.Errorf("unknown statement %T", n)
+}
+
+func (c *compiler) evalVar(n *varNode, ctx context) error {
+	name := normalizeName(n.name)
+
+	if n.isDefault {
+		if _, ok := ctx.scope.get(name); ok {
+			return nil
+		}
+	}
+
+	v, err := c.value(n.value, ctx.scope)
+	if err != nil {
+		return err
+	}
+
+	target := ctx.scope
+	if n.isGlobal {
+		target = c.global
+	} else {
+		// Assign to the innermost local scope that already has the
+		// variable; a local never changes a global without !global.
+		for s := ctx.scope; s != nil && s != c.global; s = s.parent {
+			if _, ok := s.vars[name]; ok {
+				target = s
+				break
+			}
+		}
+	}
+	target.vars[name] = v
+
+	return nil
+}
+
+func (c *compiler) evalDecl(n *declNode, ctx context) error {
+	if ctx.item == nil {
+		return fmt.Errorf("declaration %q is not inside a rule", n.property)
+	}
+
+	prop, err := c.interpolate(n.property, ctx.scope)
+	if err != nil {
+		return err
+	}
+
+	var val string
+	if strings.HasPrefix(prop, "--") {
+		// Custom properties are plain CSS apart from interpolation.
+		val, err = c.interpolate(n.value, ctx.scope)
+	} else {
+		val, err = c.declValue(n.value, ctx.scope)
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx.item.decls = append(ctx.item.decls, prop+": "+val)
+	return nil
+}
+
+func (c *compiler) evalRule(n *ruleNode, ctx context) error {
+	sel, err := c.interpolate(n.selector, ctx.scope)
+	if err != nil {
+		return err
+	}
+
+	selectors := splitList(sel)
+	if !ctx.noNest {
+		selectors = nestSelectors(ctx.selectors, selectors)
+	}
+
+	it := c.emit(&item{wrappers: ctx.wrappers, selectors: selectors})
+
+	ctx.selectors = selectors
+	ctx.item = it
+	ctx.noNest = false
+	ctx.scope = ctx.scope.child()
+
+	return c.eval(n.children, ctx)
+}
+
+func (c *compiler) evalAtRule(n *atNode, ctx context) error {
+	switch n.name {
+	case "import":
+		return c.evalImport(n, ctx)
+	case "use":
+		return c.evalUse(n, ctx)
+	case "mixin":
+		return c.evalMixin(n, ctx)
+	case "include":
+		return c.evalInclude(n, ctx)
+	case "content":
+		return c.evalContent(ctx)
+	case "for":
+		return c.evalFor(n, ctx)
+	case "each":
+		return c.evalEach(n, ctx)
+	case "debug", "warn":
+		return nil
+	case "error":
+		msg, err := c.value(n.prelude, ctx.scope)
+		if err != nil {
+			return err
+		}
+		return errors.New(unquoted(msg))
+	case "extend", "function", "return", "if", "else", "while", "forward":
+		return fmt.Errorf("@%s is not supported", n.name)
+	}
+
+	prelude, err := c.prelude(n.prelude, ctx.scope)
+	if err != nil {
+		return err
+	}
+
+	if !n.block {
+		c.emit(&item{raw: strings.TrimSpace("@" + n.name + " " + prelude + ";")})
+		return nil
+	}
+
+	switch {
+	case n.name == "media" || n.name == "supports" || n.name == "container" || n.name == "layer":
+		wrapper := "@" + n.name + " " + prelude
+		wrappers := append([]string(nil), ctx.wrappers...)
+		if last := len(wrappers) - 1; n.name == "media" && last >= 0 && strings.HasPrefix(wrappers[last], "@media ") {
+			wrappers[last] += " and " + prelude
+		} else {
+			wrappers = append(wrappers, wrapper)
+		}
+
+		ctx.wrappers = wrappers
+		ctx.item = nil
+		if ctx.selectors != nil {
+			ctx.item = c.emit(&item{wrappers: wrappers, selectors: ctx.selectors})
+		}
+
+	case strings.HasSuffix(n.name, "keyframes"):
+		ctx.wrappers = append(append([]string(nil), ctx.wrappers...), strings.TrimSpace("@"+n.name+" "+prelude))
+		ctx.selectors = nil
+		ctx.item = nil
+		ctx.noNest = true
+
+	default:
+		// A block of declarations such as @font-face or @page.
+		ctx.selectors = []string{strings.TrimSpace("@" + n.name + " " + prelude)}
+		ctx.item = c.emit(&item{wrappers: ctx.wrappers, selectors: ctx.selectors})
+		ctx.noNest = true
+	}
+
+	ctx.scope = ctx.scope.child()
+	return c.eval(n.children, ctx)
+}
+
+func (c *compiler) evalImport(n *atNode, ctx context) error {
+	for _, arg := range splitList(n.prelude) {
+		if isPlainImport(arg) {
+			c.emit(&item{raw: "@import " + arg + ";"})
+			continue
+		}
+
+		v, err := c.value(arg, ctx.scope)
+		if err != nil {
+			return err
+		}
+		if err := c.load(unquoted(v), ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *compiler) evalUse(n *atNode, ctx context) error {
+	fields := strings.Fields(n.prelude)
+	if len(fields) == 0 {
+		return fmt.Errorf("@use needs a URL")
+	}
+
+	v, err := c.value(fields[0], ctx.scope)
+	if err != nil {
+		return err
+	}
+
+	name := unquoted(v)
+	if strings.HasPrefix(name, "sass:") {
+		return nil // built-in module, its functions are always available
+	}
+
+	return c.load(name, ctx)
+}
+
+// load imports the stylesheet name, relative to the current file. A .css file
+// is inlined as is.
+func (c *compiler) load(name string, ctx context) error {
+	p, err := c.resolve(ctx.file, name)
+	if err != nil {
+		return err
+	}
+
+	if path.Ext(p) == ".css" {
+		src, err := fs.ReadFile(c.fsys, p)
+		if err != nil {
+			return err
+		}
+		if !c.read[p] {
+			c.read[p] = true
+			c.files = append(c.files, p)
+		}
+		c.emit(&item{raw: strings.TrimSpace(string(src))})
+		return nil
+	}
+
+	return c.importFile(p, ctx)
+}
+
+// resolve finds the file imported as name from the file at from, trying name
+// itself, the partial "_name", and the ".scss" and ".css" extensions.
+func (c *compiler) resolve(from, name string) (string, error) {
+	p := path.Join(path.Dir(from), name)
+	dir, base := path.Split(p)
+	partial := path.Join(dir, "_"+base)
+
+	var candidates []string
+	switch path.Ext(p) {
+	case ".scss", ".css":
+		candidates = []string{p, partial}
+	default:
+		candidates = []string{p + ".scss", partial + ".scss", p + ".css", path.Join(p, "_index.scss"), path.Join(p, "index.scss")}
+	}
+
+	for _, candidate := range candidates {
+		if !fs.ValidPath(candidate) {
+			continue
+		}
+		if info, err := fs.Stat(c.fsys, candidate); err == nil && info.Mode().IsRegular() {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("cannot find stylesheet to import: %q", name)
+}
+
+// isPlainImport reports whether an @import argument is a plain CSS import
+// left for the browser, e.g. url(...) or an http URL.
+func isPlainImport(arg string) bool {
+	s := strings.Trim(arg, `"'`)
+
+	return strings.HasPrefix(arg, "url(") || strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "//")
+}
+
+func (c *compiler) evalMixin(n *atNode, ctx context) error {
+	name, args := splitCall(n.prelude)
+	if name == "" {
+		return fmt.Errorf("@mixin needs a name")
+	}
+
+	m := &mixin{file: ctx.file, body: n.children}
+	for _, arg := range splitList(args) {
+		p := param{name: arg}
+		if i := strings.IndexByte(arg, ':'); i >= 0 {
+			p = param{name: strings.TrimSpace(arg[:i]), def: strings.TrimSpace(arg[i+1:])}
+		}
+		if !strings.HasPrefix(p.name, "$") {
+			return fmt.Errorf("invalid parameter %q of mixin %s", arg, name)
+		}
+		p.name = normalizeName(p.name[1:])
+		m.params = append(m.params, p)
+	}
+
+	c.mixins[normalizeName(name)] = m
+	return nil
+}
+
+var keywordArg = regexp.MustCompile(`^\$([\w-]+)\s*:`)
+
+func (c *compiler) evalInclude(n *atNode, ctx context) error {
+	name, args := splitCall(n.prelude)
+	m, ok := c.mixins[normalizeName(name)]
+	if !ok {
+		return fmt.Errorf("undefined mixin %s", name)
+	}
+
+	vars := c.global.child()
+	var positional []value
+	keyword := make(map[string]value)
+	for _, arg := range splitList(args) {
+		if match := keywordArg.FindStringSubmatch(arg); match != nil {
+			v, err := c.value(arg[len(match[0]):], ctx.scope)
+			if err != nil {
+				return err
+			}
+			keyword[normalizeName(match[1])] = v
+			continue
+		}
+		v, err := c.value(arg, ctx.scope)
+		if err != nil {
+			return err
+		}
+		positional = append(positional, v)
+	}
+	if len(positional) > len(m.params) {
+		return fmt.Errorf("mixin %s takes %d arguments, got %d", name, len(m.params), len(positional))
+	}
+
+	for i, p := range m.params {
+		switch v, ok := keyword[p.name]; {
+		case i < len(positional):
+			vars.vars[p.name] = positional[i]
+		case ok:
+			vars.vars[p.name] = v
+		case p.def != "":
+			v, err := c.value(p.def, vars)
+			if err != nil {
+				return err
+			}
+			vars.vars[p.name] = v
+		default:
+			return fmt.Errorf("missing argument $%s of mixin %s", p.name, name)
+		}
+	}
+
+	if n.block {
+		ctx.content = &content{file: ctx.file, nodes: n.children, scope: ctx.scope, outer: ctx.content}
+	} else {
+		ctx.content = nil
+	}
+	ctx.file = m.file
+	ctx.scope = vars
+
+	return c.eval(m.body, ctx)
+}
+
+func (c *compiler) evalContent(ctx context) error {
+	if ctx.content == nil {
+		return nil
+	}
+
+	cb := ctx.content
+	ctx.file = cb.file
+	ctx.scope = cb.scope.child()
+	ctx.content = cb.outer
+
+	return c.eval(cb.nodes, ctx)
+}
+
+var forPrelude = regexp.MustCompile(`^\$([\w-]+)\s+from\s+(.+?)\s+(through|to)\s+(.+)$`)
+
+func (c *compiler) evalFor(n *atNode, ctx context) error {
+	match := forPrelude.FindStringSubmatch(n.prelude)
+	if match == nil {
+		return fmt.Errorf("invalid @for %q, expected \"$var from <start> through|to <end>\"", n.prelude)
+	}
+
+	bounds := make([]number, 2)
+	for i, expr := range []string{match[2], match[4]} {
+		v, err := c.value(expr, ctx.scope)
+		if err != nil {
+			return err
+		}
+		num, ok := v.(number)
+		if !ok {
+			return fmt.Errorf("@for bound %s is not a number", v.css())
+		}
+		bounds[i] = num
+	}
+
+	from, to := int(bounds[0].v), int(bounds[1].v)
+	step := 1
+	if to < from {
+		step = -1
+	}
+	if match[3] == "through" {
+		to += step
+	}
+
+	for i := from; i != to; i += step {
+		loop := ctx
+		loop.scope = ctx.scope.child()
+		loop.scope.vars[normalizeName(match[1])] = number{v: float64(i), unit: bounds[0].unit}
+		if err := c.eval(n.children, loop); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *compiler) evalEach(n *atNode, ctx context) error {
+	i := strings.Index(n.prelude, " in ")
+	if i < 0 {
+		return fmt.Errorf("invalid @each %q, expected \"$var in <list>\"", n.prelude)
+	}
+
+	var names []string
+	for _, name := range splitList(n.prelude[:i]) {
+		if !strings.HasPrefix(name, "$") {
+			return fmt.Errorf("invalid @each variable %q", name)
+		}
+		names = append(names, normalizeName(name[1:]))
+	}
+
+	v, err := c.value(n.prelude[i+4:], ctx.scope)
+	if err != nil {
+		return err
+	}
+
+	for _, elem := range items(v) {
+		loop := ctx
+		loop.scope = ctx.scope.child()
+
+		if len(names) == 1 {
+			loop.scope.vars[names[0]] = elem
+		} else {
+			parts := items(elem)
+			for j, name := range names {
+				if j < len(parts) {
+					loop.scope.vars[name] = parts[j]
+				} else {
+					loop.scope.vars[name] = str{}
+				}
+			}
+		}
+
+		if err := c.eval(n.children, loop); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// emit appends it to the output and returns it.
+func (c *compiler) emit(it *item) *item {
+	c.out = append(c.out, it)
+	return it
+}
+
+// value evaluates the expression src after resolving its interpolation.
+func (c *compiler) value(src string, vars *scope) (value, error) {
+	src, err := c.interpolate(src, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return evalExpr(src, vars)
+}
+
+// sassFunc matches the SCSS functions that make a declaration value an
+// expression to evaluate.
+var sassFunc = regexp.MustCompile(`(^|[^\w-])(math\.[\w-]+|map\.[\w-]+|percentage|map-get|nth|length|unquote|quote)\(`)
+
+// declValue returns the CSS text of a declaration value. A value without
+// variables or SCSS functions is plain CSS and kept as written.
+func (c *compiler) declValue(src string, vars *scope) (string, error) {
+	src, err := c.interpolate(src, vars)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.Contains(src, "$") && !sassFunc.MatchString(src) {
+		return src, nil
+	}
+
+	v, err := evalExpr(src, vars)
+	if err != nil {
+		return "", err
+	}
+
+	return v.css(), nil
+}
+
+var variableRef = regexp.MustCompile(`\$[\w-]+`)
+
+// prelude resolves the interpolation and the variables of an at-rule prelude,
+// e.g. "(min-width: $medium)".
+func (c *compiler) prelude(src string, vars *scope) (string, error) {
+	src, err := c.interpolate(src, vars)
+	if err != nil {
+		return "", err
+	}
+
+	var undefined error
+	src = variableRef.ReplaceAllStringFunc(src, func(ref string) string {
+		v, ok := vars.get(ref[1:])
+		if !ok {
+			undefined = fmt.Errorf("undefined variable %s", ref)
+			return ref
+		}
+		return unquoted(v)
+	})
+
+	return src, undefined
+}
+
+// interpolate replaces every #{expr} in src with the unquoted value of expr.
+func (c *compiler) interpolate(src string, vars *scope) (string, error) {
+	var b strings.Builder
+
+	for {
+		start := strings.Index(src, "#{")
+		if start < 0 {
+			b.WriteString(src)
+			return b.String(), nil
+		}
+
+		depth, end := 0, -1
+		for i := start + 1; i < len(src) && end < 0; i++ {
+			switch src[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					end = i
+				}
+			}
+		}
+		if end < 0 {
+			return "", fmt.Errorf("unterminated interpolation in %q", src)
+		}
+
+		v, err := c.value(src[start+2:end], vars)
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteString(src[:start])
+		b.WriteString(unquoted(v))
+		src = src[end+1:]
+	}
+}
+
+// splitList splits src at the commas outside of parentheses and strings,
+// trimming each part.
+func splitList(src string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+
+	for i := 0; i < len(src); i++ {
+		ch := src[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '(' || ch == '[' || ch == '{':
+			depth++
+		case ch == ')' || ch == ']' || ch == '}':
+			depth--
+		case ch == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(src[start:i]))
+			start = i + 1
+		}
+	}
+
+	if last := strings.TrimSpace(src[start:]); last != "" || len(parts) > 0 {
+		parts = append(parts, last)
+	}
+
+	return parts
+}
+
+// splitCall splits "name(args)" into its name and the text between the
+// parentheses.
+func splitCall(src string) (string, string) {
+	i := strings.IndexByte(src, '(')
+	if i < 0 {
+		return strings.TrimSpace(src), ""
+	}
+
+	args := src[i+1:]
+	if j := strings.LastIndexByte(args, ')'); j >= 0 {
+		args = args[:j]
+	}
+
+	return strings.TrimSpace(src[:i]), args
+}
+
+// nestSelectors joins every nested selector with every parent selector,
+// replacing & with the parent, or prefixing the parent as a descendant.
+func nestSelectors(parents, nested []string) []string {
+	if len(parents) == 0 {
+		return nested
+	}
+
+	var out []string
+	for _, parent := range parents {
+		for _, sel := range nested {
+			if strings.Contains(sel, "&") {
+				out = append(out, strings.Replace(sel, "&", parent, -1))
+			} else {
+				out = append(out, parent+" "+sel)
+			}
+		}
+	}
+
+	return out
+}
+
+// render writes the output items as CSS, grouping consecutive items of the
+// same at-rules into one block and leaving out rules without declarations.
+func render(items []*item) string {
+	var b strings.Builder
+	var open []string
+
+	closeTo := func(n int) {
+		for len(open) > n {
+			open = open[:len(open)-1]
+			b.WriteString(strings.Repeat("  ", len(open)) + "}\n")
+		}
+	}
+
+	for _, it := range items {
+		if it.raw == "" && len(it.decls) == 0 {
+			continue
+		}
+
+		common := 0
+		for common < len(open) && common < len(it.wrappers) && open[common] == it.wrappers[common] {
+			common++
+		}
+		closeTo(common)
+
+		if len(open) == 0 && b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		for _, w := range it.wrappers[len(open):] {
+			b.WriteString(strings.Repeat("  ", len(open)) + w + " {\n")
+			open = append(open, w)
+		}
+
+		indent := strings.Repeat("  ", len(open))
+		if it.raw != "" {
+			for _, line := range strings.Split(it.raw, "\n") {
+				b.WriteString(indent + line + "\n")
+			}
+			continue
+		}
+
+		b.WriteString(indent + strings.Join(it.selectors, ",\n"+indent) + " {\n")
+		for _, decl := range it.decls {
+			b.WriteString(indent + "  " + decl + ";\n")
+		}
+		b.WriteString(indent + "}\n")
+	}
+	closeTo(0)
+
+	return b.String()
+}
@@ -0,0 +1,292 @@
+package scss
+
+import (
+	"fmt"
+	"strings"
+)
+
+// node is a statement of a parsed stylesheet.
+type node interface {
+	position() int
+}
+
+// ruleNode is a style rule, "selector { children }".
+type ruleNode struct {
+	line     int
+	selector string
+	children []node
+}
+
+// declNode is a declaration, "property: value".
+type declNode struct {
+	line     int
+	property string
+	value    string
+}
+
+// varNode is a variable assignment, "$name: value [!default] [!global]".
+type varNode struct {
+	line      int
+	name      string
+	value     string
+	isDefault bool
+	isGlobal  bool
+}
+
+// atNode is an at-rule, with a block when block is true, e.g. "@media
+// prelude { children }" or "@import prelude".
+type atNode struct {
+	line     int
+	name     string
+	prelude  string
+	block    bool
+	children []node
+}
+
+func (n *ruleNode) position() int { return n.line }
+func (n *declNode) position() int { return n.line }
+func (n *varNode) position() int  { return n.line }
+func (n *atNode) position() int   { return n.line }
+
+type parser struct {
+	file string
+	src  string
+	pos  int
+	line int
+}
+
+// parse parses the SCSS source of file into statements.
+func parse(file string, src string) ([]node, error) {
+	p := &parser{file: file, src: src, line: 1}
+	return p.parseBlock(false)
+}
+
+func (p *parser) errorf(line int, format string, args ...interface{}) error {
+	return &Error{File: p.file, Line: line, Err: fmt.Errorf(format, args...)}
+}
+
+// parseBlock parses statements up to the '}' closing the block, or to the end
+// of the source when nested is false.
+func (p *parser) parseBlock(nested bool) ([]node, error) {
+	var nodes []node
+
+	for {
+		p.skipSpaceAndComments()
+		if p.pos >= len(p.src) {
+			if nested {
+				return nil, p.errorf(p.line, "expected \"}\"")
+			}
+			return nodes, nil
+		}
+
+		switch p.src[p.pos] {
+		case '}':
+			if !nested {
+				return nil, p.errorf(p.line, "unexpected \"}\"")
+			}
+			p.pos++
+			return nodes, nil
+		case ';':
+			p.pos++
+			continue
+		}
+
+		line := p.line
+		text, end, err := p.readStatement()
+		if err != nil {
+			return nil, err
+		}
+
+		if end == '{' {
+			children, err := p.parseBlock(true)
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(text, "@") {
+				name, prelude := splitAtRule(text)
+				nodes = append(nodes, &atNode{line: line, name: name, prelude: prelude, block: true, children: children})
+			} else {
+				nodes = append(nodes, &ruleNode{line: line, selector: text, children: children})
+			}
+			continue
+		}
+
+		n, err := p.statement(line, text)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+}
+
+// statement parses a statement without a block.
+func (p *parser) statement(line int, text string) (node, error) {
+	switch {
+	case strings.HasPrefix(text, "@"):
+		name, prelude := splitAtRule(text)
+		return &atNode{line: line, name: name, prelude: prelude}, nil
+
+	case strings.HasPrefix(text, "$"):
+		colon := strings.IndexByte(text, ':')
+		if colon < 0 {
+			return nil, p.errorf(line, "expected \":\" in variable declaration %q", text)
+		}
+		n := &varNode{line: line, name: strings.TrimSpace(text[1:colon]), value: strings.TrimSpace(text[colon+1:])}
+		for {
+			switch {
+			case strings.HasSuffix(n.value, "!default"):
+				n.isDefault = true
+				n.value = strings.TrimSpace(strings.TrimSuffix(n.value, "!default"))
+				continue
+			case strings.HasSuffix(n.value, "!global"):
+				n.isGlobal = true
+				n.value = strings.TrimSpace(strings.TrimSuffix(n.value, "!global"))
+				continue
+			}
+			break
+		}
+		return n, nil
+	}
+
+	colon := strings.IndexByte(text, ':')
+	if colon < 0 {
+		return nil, p.errorf(line, "expected a declaration, got %q", text)
+	}
+
+	return &declNode{line: line, property: strings.TrimSpace(text[:colon]), value: strings.TrimSpace(text[colon+1:])}, nil
+}
+
+// readStatement reads up to the next ';', '{' or '}' outside of strings,
+// parentheses and interpolation. A ';' or '{' is consumed and returned as end,
+// a '}' is left for parseBlock. Comments are removed from the text.
+func (p *parser) readStatement() (string, byte, error) {
+	var b strings.Builder
+	parens := 0
+	line := p.line
+
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+
+		switch {
+		case c == '\n':
+			p.line++
+
+		case c == '"' || c == '\'':
+			end := p.pos + 1
+			for end < len(p.src) && p.src[end] != c {
+				if p.src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(p.src) {
+				return "", 0, p.errorf(line, "unterminated string")
+			}
+			b.WriteString(p.src[p.pos : end+1])
+			p.pos = end + 1
+			continue
+
+		case c == '#' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '{':
+			end, err := p.interpolationEnd(line)
+			if err != nil {
+				return "", 0, err
+			}
+			b.WriteString(p.src[p.pos:end])
+			p.pos = end
+			continue
+
+		case c == '/' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '*':
+			p.skipBlockComment()
+			continue
+
+		case c == '/' && parens == 0 && p.pos+1 < len(p.src) && p.src[p.pos+1] == '/':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+
+		case c == '(':
+			parens++
+		case c == ')':
+			parens--
+
+		case parens == 0 && (c == ';' || c == '{'):
+			p.pos++
+			return strings.TrimSpace(b.String()), c, nil
+		case parens == 0 && c == '}':
+			return strings.TrimSpace(b.String()), c, nil
+		}
+
+		b.WriteByte(c)
+		p.pos++
+	}
+
+	return strings.TrimSpace(b.String()), 0, nil
+}
+
+// interpolationEnd returns the position after the '}' closing the
+// interpolation starting at p.pos.
+func (p *parser) interpolationEnd(line int) (int, error) {
+	depth := 0
+	for i := p.pos + 1; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		case '\n':
+			p.line++
+		}
+	}
+
+	return 0, p.errorf(line, "unterminated interpolation")
+}
+
+func (p *parser) skipSpaceAndComments() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\n':
+			p.line++
+			p.pos++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			p.pos++
+		case c == '/' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '/':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == '/' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '*':
+			p.skipBlockComment()
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) skipBlockComment() {
+	end := strings.Index(p.src[p.pos+2:], "*/")
+	if end < 0 {
+		p.line += strings.Count(p.src[p.pos:], "\n")
+		p.pos = len(p.src)
+		return
+	}
+	end += p.pos + 4
+	p.line += strings.Count(p.src[p.pos:end], "\n")
+	p.pos = end
+}
+
+// splitAtRule splits "@name prelude" into its name and prelude.
+func splitAtRule(text string) (string, string) {
+	text = text[1:]
+	i := strings.IndexFunc(text, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '(' || r == '"' || r == '\''
+	})
+	if i < 0 {
+		return text, ""
+	}
+
+	return text[:i], strings.TrimSpace(text[i:])
+}
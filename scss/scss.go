@@ -0,0 +1,67 @@
+// Package scss compiles a subset of SCSS to CSS in pure Go.
+//
+// The supported subset covers what themes commonly need:
+//
+//   - variables, including !default and !global, with numbers, strings,
+//     lists and maps
+//   - nested rules, the parent selector & and nested @media and @supports
+//   - #{} interpolation in selectors, properties, values and at-rules
+//   - @mixin and @include, with default and keyword arguments and @content
+//   - @import of partials, "_name.scss", and of plain .css files, which are
+//     inlined, and @use of files and of the built-in "sass:" modules
+//   - @for and @each, including @each over the pairs of a map
+//   - arithmetic with units, and the functions math.div, percentage, round,
+//     ceil, floor, abs, unquote, quote, map-get, nth and length
+//
+// A slash is always a CSS separator, as in "font: 12px/1.5", use math.div to
+// divide. Functions that are not SCSS functions, such as rgb(), var() and
+// calc(), are plain CSS and output as written. @extend, @function, @if and
+// @while are not supported and fail to compile.
+package scss
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// Error is a compile error at a line of a source file.
+type Error struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Result is a compiled stylesheet.
+type Result struct {
+	CSS []byte
+
+	// Files lists the path of every file read to compile the stylesheet,
+	// starting with the stylesheet itself, so that a caller can tell when the
+	// result is out of date.
+	Files []string
+}
+
+// Compile compiles the SCSS file at name within fsys to CSS. Imports are
+// resolved relative to the importing file.
+func Compile(fsys fs.FS, name string) (*Result, error) {
+	c := &compiler{
+		fsys:   fsys,
+		read:   make(map[string]bool),
+		mixins: make(map[string]*mixin),
+		global: &scope{vars: make(map[string]value)},
+	}
+
+	if err := c.importFile(name, context{scope: c.global}); err != nil {
+		return nil, err
+	}
+
+	return &Result{CSS: []byte(render(c.out)), Files: c.files}, nil
+}
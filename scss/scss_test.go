@@ -0,0 +1,126 @@
+package scss_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ukiahsmith/lemur/scss"
+)
+
+func TestCompile(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Source   string
+		Expected string
+	}{
+		{
+			Name:     "plain css",
+			Source:   "a { color: red; font: 12px/1.5 'Helvetica Neue', sans-serif; }",
+			Expected: "a {\n  color: red;\n  font: 12px/1.5 'Helvetica Neue', sans-serif;\n}\n",
+		},
+		{
+			Name:     "variables",
+			Source:   "$gap: 1.8rem;\n$gap: 2rem !default;\na { margin: 0 $gap; padding: $gap * 2; }",
+			Expected: "a {\n  margin: 0 1.8rem;\n  padding: 3.6rem;\n}\n",
+		},
+		{
+			Name:     "nesting and parent selector",
+			Source:   ".row { display: flex; .column, &.wide { width: 100%; } a:hover & { color: red; } }",
+			Expected: ".row {\n  display: flex;\n}\n\n.row .column,\n.row.wide {\n  width: 100%;\n}\n\na:hover .row {\n  color: red;\n}\n",
+		},
+		{
+			Name:     "nested media",
+			Source:   "$medium: 640px;\n.nav { float: none; @media (min-width: $medium) { float: left; } }",
+			Expected: ".nav {\n  float: none;\n}\n\n@media (min-width: 640px) {\n  .nav {\n    float: left;\n  }\n}\n",
+		},
+		{
+			Name:     "mixins",
+			Source:   "@mixin button($color, $pad: 1em) { color: $color; padding: $pad; @content; }\n.buy { @include button(red) { border: 0; } }\n.cancel { @include button($pad: 0, $color: grey); }",
+			Expected: ".buy {\n  color: red;\n  padding: 1em;\n  border: 0;\n}\n\n.cancel {\n  color: grey;\n  padding: 0;\n}\n",
+		},
+		{
+			Name:     "interpolation and each over a map",
+			Source:   "$sizes: ('small': 0, 'large': 1024px);\n@each $name, $size in $sizes { .hide-#{$name} { min-width: $size; } }",
+			Expected: ".hide-small {\n  min-width: 0;\n}\n\n.hide-large {\n  min-width: 1024px;\n}\n",
+		},
+		{
+			Name:     "for and math",
+			Source:   "@use \"sass:math\";\n@for $i from 1 through 3 { .col-#{$i} { width: percentage(math.div($i, 3)); margin: math.div(1.8rem, -2); } }",
+			Expected: ".col-1 {\n  width: 33.3333333333%;\n  margin: -0.9rem;\n}\n\n.col-2 {\n  width: 66.6666666667%;\n  margin: -0.9rem;\n}\n\n.col-3 {\n  width: 100%;\n  margin: -0.9rem;\n}\n",
+		},
+		{
+			Name:     "css functions",
+			Source:   "$gap: 2rem;\na { padding: 0 var(--gap, $gap); width: calc(100% - #{$gap}); color: rgb(1, 2, 3); }",
+			Expected: "a {\n  padding: 0 var(--gap, 2rem);\n  width: calc(100% - 2rem);\n  color: rgb(1, 2, 3);\n}\n",
+		},
+		{
+			Name:     "comments",
+			Source:   "// line\na { /* block */ color: red; // trailing\n background: url(http://example.com/a.png); }",
+			Expected: "a {\n  color: red;\n  background: url(http://example.com/a.png);\n}\n",
+		},
+		{
+			Name:     "keyframes and font-face",
+			Source:   "@keyframes spin { from { transform: rotate(0); } to { transform: rotate(360deg); } }\n@font-face { font-family: Lemur; }",
+			Expected: "@keyframes spin {\n  from {\n    transform: rotate(0);\n  }\n  to {\n    transform: rotate(360deg);\n  }\n}\n\n@font-face {\n  font-family: Lemur;\n}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			fsys := fstest.MapFS{"style.scss": &fstest.MapFile{Data: []byte(tc.Source)}}
+
+			res, err := scss.Compile(fsys, "style.scss")
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+
+			if string(res.CSS) != tc.Expected {
+				t.Errorf("Expected\n%s\nbut got\n%s", tc.Expected, res.CSS)
+			}
+		})
+	}
+}
+
+func TestCompile_Imports(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sass/style.scss":          &fstest.MapFile{Data: []byte("@import \"vars\", \"../vendor/reset.css\";\n@import 'lib/grid';\nbody { color: $text; }")},
+		"sass/_vars.scss":          &fstest.MapFile{Data: []byte("$text: #222;")},
+		"sass/lib/_grid.scss":      &fstest.MapFile{Data: []byte("@import 'mixins';\n.row { @include flex; }")},
+		"sass/lib/_mixins.scss":    &fstest.MapFile{Data: []byte("@mixin flex { display: flex; }")},
+		"vendor/reset.css":         &fstest.MapFile{Data: []byte("html{margin:0}\n")},
+		"sass/unused/_other.scss":  &fstest.MapFile{Data: []byte("a { b: c; }")},
+		"sass/broken.scss":         &fstest.MapFile{Data: []byte("a {\n  color: $missing;\n}")},
+		"sass/missing-import.scss": &fstest.MapFile{Data: []byte("@import 'nope';")},
+	}
+
+	res, err := scss.Compile(fsys, "sass/style.scss")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	expected := "html{margin:0}\n\n.row {\n  display: flex;\n}\n\nbody {\n  color: #222;\n}\n"
+	if string(res.CSS) != expected {
+		t.Errorf("Expected\n%s\nbut got\n%s", expected, res.CSS)
+	}
+
+	files := strings.Join(res.Files, " ")
+	expectedFiles := "sass/style.scss sass/_vars.scss vendor/reset.css sass/lib/_grid.scss sass/lib/_mixins.scss"
+	if files != expectedFiles {
+		t.Errorf("Expected files %q, but got %q", expectedFiles, files)
+	}
+
+	_, err = scss.Compile(fsys, "sass/broken.scss")
+	var scssErr *scss.Error
+	if !errors.As(err, &scssErr) {
+		t.Fatalf("Expected a *scss.Error, but got %v", err)
+	}
+	if scssErr.File != "sass/broken.scss" || scssErr.Line != 2 {
+		t.Errorf("Expected the error at sass/broken.scss:2, but got %s:%d", scssErr.File, scssErr.Line)
+	}
+
+	if _, err := scss.Compile(fsys, "sass/missing-import.scss"); err == nil || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("Expected an error for the missing import, but got %v", err)
+	}
+}
@@ -0,0 +1,726 @@
+package scss
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// value is the result of evaluating a SCSS expression.
+type value interface {
+	css() string
+}
+
+// number is a number with an optional unit, e.g. 1.8rem or 12.
+type number struct {
+	v    float64
+	unit string
+}
+
+// str is a quoted or unquoted string. Identifiers, colors and anything that is
+// not evaluated further are unquoted strings.
+type str struct {
+	s     string
+	quote byte
+}
+
+// list is a space or comma separated list.
+type list struct {
+	items []value
+	sep   string
+}
+
+// mapValue is an ordered map, "(key: value, ...)".
+type mapValue struct {
+	keys []value
+	vals []value
+}
+
+func (n number) css() string {
+	v := math.Round(n.v*1e10) / 1e10
+	if v == 0 {
+		v = 0 // drop the sign of -0
+	}
+
+	return strconv.FormatFloat(v, 'f', -1, 64) + n.unit
+}
+
+func (s str) css() string {
+	if s.quote == 0 {
+		return s.s
+	}
+
+	return string(s.quote) + s.s + string(s.quote)
+}
+
+func (l list) css() string {
+	parts := make([]string, len(l.items))
+	for i, item := range l.items {
+		parts[i] = item.css()
+	}
+
+	return strings.Join(parts, l.sep)
+}
+
+func (m mapValue) css() string {
+	parts := make([]string, len(m.keys))
+	for i := range m.keys {
+		parts[i] = m.keys[i].css() + ": " + m.vals[i].css()
+	}
+
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// unquoted returns the CSS text of v with the quotes of a string removed, as
+// used for interpolation.
+func unquoted(v value) string {
+	if s, ok := v.(str); ok {
+		return s.s
+	}
+
+	return v.css()
+}
+
+// items returns the elements of v when iterated with @each.
+func items(v value) []value {
+	switch v := v.(type) {
+	case list:
+		return v.items
+	case mapValue:
+		out := make([]value, len(v.keys))
+		for i := range v.keys {
+			out[i] = list{items: []value{v.keys[i], v.vals[i]}, sep: " "}
+		}
+		return out
+	}
+
+	return []value{v}
+}
+
+type tokenKind int
+
+const (
+	tokNumber tokenKind = iota
+	tokString
+	tokIdent
+	tokVar
+	tokFunc // an identifier directly followed by "("
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+	tokColon
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	num   number
+	quote byte
+	space bool // whitespace precedes the token
+}
+
+// tokenize splits an expression into tokens. Interpolation must already be
+// resolved.
+func tokenize(src string) ([]token, error) {
+	var toks []token
+	space := false
+
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			space = true
+			i++
+			continue
+
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(src) && src[end] != c {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("unterminated string in %q", src)
+			}
+			toks = append(toks, token{kind: tokString, text: src[i+1 : end], quote: c, space: space})
+			i = end + 1
+
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])) ||
+			((c == '-' || c == '+') && startsNumber(src, i+1) && !afterOperand(toks, space)):
+			end := i + 1
+			for end < len(src) && (isDigit(src[end]) || (src[end] == '.' && end+1 < len(src) && isDigit(src[end+1]))) {
+				end++
+			}
+			v, err := strconv.ParseFloat(src[i:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", src[i:end])
+			}
+			unitEnd := end
+			if unitEnd < len(src) && src[unitEnd] == '%' {
+				unitEnd++
+			} else {
+				for unitEnd < len(src) && isUnitChar(src[unitEnd]) {
+					unitEnd++
+				}
+			}
+			toks = append(toks, token{kind: tokNumber, text: src[i:unitEnd], num: number{v: v, unit: src[end:unitEnd]}, space: space})
+			i = unitEnd
+
+		case c == '$':
+			end := i + 1
+			for end < len(src) && isNameChar(src[end]) {
+				end++
+			}
+			if end == i+1 {
+				return nil, fmt.Errorf("expected a variable name in %q", src)
+			}
+			toks = append(toks, token{kind: tokVar, text: src[i+1 : end], space: space})
+			i = end
+
+		case isNameStart(c) || c == '#' || c == '!' || (c == '-' && i+1 < len(src) && (isNameStart(src[i+1]) || src[i+1] == '-')):
+			end := i + 1
+			for end < len(src) && (isNameChar(src[end]) || (src[end] == '.' && end+1 < len(src) && isNameStart(src[end+1]))) {
+				end++
+			}
+			name := src[i:end]
+			if end < len(src) && src[end] == '(' {
+				if strings.EqualFold(name, "url") {
+					close := strings.IndexByte(src[end:], ')')
+					if close < 0 {
+						return nil, fmt.Errorf("unterminated url( in %q", src)
+					}
+					toks = append(toks, token{kind: tokIdent, text: src[i : end+close+1], space: space})
+					i = end + close + 1
+					break
+				}
+				toks = append(toks, token{kind: tokFunc, text: name, space: space})
+				i = end + 1
+				break
+			}
+			toks = append(toks, token{kind: tokIdent, text: name, space: space})
+			i = end
+
+		case c == '(':
+			toks = append(toks, token{kind: tokLParen, text: "(", space: space})
+			i++
+		case c == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")", space: space})
+			i++
+		case c == ',':
+			toks = append(toks, token{kind: tokComma, text: ",", space: space})
+			i++
+		case c == ':':
+			toks = append(toks, token{kind: tokColon, text: ":", space: space})
+			i++
+		case c == '+' || c == '-' || c == '*' || c == '/' || c == '%' || c == '=':
+			toks = append(toks, token{kind: tokOp, text: string(c), space: space})
+			i++
+
+		default:
+			// Anything else, e.g. "U+0025" ranges or IE filters, is kept as an
+			// unquoted string.
+			toks = append(toks, token{kind: tokIdent, text: string(c), space: space})
+			i++
+		}
+
+		space = false
+	}
+
+	return toks, nil
+}
+
+// afterOperand reports whether a sign at this point is a binary operator
+// rather than part of a number, as in "$a -1" versus "$a - 1" and "1 -1".
+func afterOperand(toks []token, space bool) bool {
+	if len(toks) == 0 || space {
+		return false
+	}
+
+	switch toks[len(toks)-1].kind {
+	case tokNumber, tokVar, tokRParen, tokIdent, tokString:
+		return true
+	}
+
+	return false
+}
+
+func startsNumber(src string, i int) bool {
+	if i >= len(src) {
+		return false
+	}
+
+	return isDigit(src[i]) || (src[i] == '.' && i+1 < len(src) && isDigit(src[i+1]))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || isDigit(c) || c == '-'
+}
+
+func isUnitChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// exprParser evaluates a token stream.
+type exprParser struct {
+	toks []token
+	pos  int
+	vars *scope
+}
+
+// evalExpr evaluates the expression src, which must not contain
+// interpolation, with the variables of vars.
+func evalExpr(src string, vars *scope) (value, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return str{}, nil
+	}
+
+	p := &exprParser{toks: toks, vars: vars}
+	v, err := p.commaList()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q in %q", p.toks[p.pos].text, src)
+	}
+
+	return v, nil
+}
+
+func (p *exprParser) peek() (token, bool) {
+	if p.pos >= len(p.toks) {
+		return token{}, false
+	}
+
+	return p.toks[p.pos], true
+}
+
+// commaList parses "a, b, c".
+func (p *exprParser) commaList() (value, error) {
+	first, err := p.spaceList()
+	if err != nil {
+		return nil, err
+	}
+
+	vals := []value{first}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != tokComma {
+			break
+		}
+		p.pos++
+		if t, ok := p.peek(); !ok || t.kind == tokRParen {
+			break // trailing comma
+		}
+		v, err := p.spaceList()
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, v)
+	}
+
+	if len(vals) == 1 {
+		return first, nil
+	}
+
+	return list{items: vals, sep: ", "}, nil
+}
+
+// spaceList parses "a b c".
+func (p *exprParser) spaceList() (value, error) {
+	var vals []value
+
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind == tokComma || t.kind == tokRParen || t.kind == tokColon {
+			break
+		}
+		v, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, v)
+	}
+
+	switch len(vals) {
+	case 0:
+		return list{sep: " "}, nil
+	case 1:
+		return vals[0], nil
+	}
+
+	return list{items: vals, sep: " "}, nil
+}
+
+// sum parses "a + b" and "a - b". A sign only subtracts when it is spaced on
+// both sides or on neither, so that "1px -1px" stays a list.
+func (p *exprParser) sum() (value, error) {
+	left, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != tokOp || (t.text != "+" && t.text != "-") {
+			return left, nil
+		}
+		if p.pos+1 < len(p.toks) && t.space != p.toks[p.pos+1].space {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		left = arithmetic(t.text, left, right, t.space)
+	}
+}
+
+// product parses "a * b", "a % b" and "a / b". A slash is kept as a CSS
+// separator, as in "12px/1.5", use math.div to divide.
+func (p *exprParser) product() (value, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != tokOp || (t.text != "*" && t.text != "/" && t.text != "%") {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = arithmetic(t.text, left, right, t.space)
+	}
+}
+
+func (p *exprParser) unary() (value, error) {
+	t, ok := p.peek()
+	if ok && t.kind == tokOp && (t.text == "-" || t.text == "+") {
+		p.pos++
+		v, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := v.(number); ok {
+			if t.text == "-" {
+				n.v = -n.v
+			}
+			return n, nil
+		}
+		return str{s: t.text + v.css()}, nil
+	}
+
+	return p.primary()
+}
+
+func (p *exprParser) primary() (value, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+
+	switch t.kind {
+	case tokNumber:
+		return t.num, nil
+	case tokString:
+		return str{s: t.text, quote: t.quote}, nil
+	case tokIdent:
+		return str{s: t.text}, nil
+	case tokVar:
+		v, ok := p.vars.get(t.text)
+		if !ok {
+			return nil, fmt.Errorf("undefined variable $%s", t.text)
+		}
+		return v, nil
+	case tokLParen:
+		return p.parens()
+	case tokFunc:
+		args, err := p.args()
+		if err != nil {
+			return nil, err
+		}
+		return call(t.text, args)
+	}
+
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// parens parses a parenthesized expression or map after its "(".
+func (p *exprParser) parens() (value, error) {
+	if t, ok := p.peek(); ok && t.kind == tokRParen {
+		p.pos++
+		return list{sep: " "}, nil
+	}
+
+	start := p.pos
+	first, err := p.spaceList()
+	if err != nil {
+		return nil, err
+	}
+
+	if t, ok := p.peek(); ok && t.kind == tokColon {
+		m := mapValue{}
+		key := first
+		for {
+			if t, ok := p.peek(); !ok || t.kind != tokColon {
+				return nil, fmt.Errorf("expected \":\" in map")
+			}
+			p.pos++
+			val, err := p.spaceList()
+			if err != nil {
+				return nil, err
+			}
+			m.keys = append(m.keys, key)
+			m.vals = append(m.vals, val)
+
+			t, ok := p.peek()
+			if ok && t.kind == tokComma {
+				p.pos++
+				t, ok = p.peek()
+			}
+			if !ok {
+				return nil, fmt.Errorf("expected \")\"")
+			}
+			if t.kind == tokRParen {
+				p.pos++
+				return m, nil
+			}
+			key, err = p.spaceList()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	p.pos = start // not a map, reparse as a list
+	v, err := p.commaList()
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := p.peek(); !ok || t.kind != tokRParen {
+		return nil, fmt.Errorf("expected \")\"")
+	}
+	p.pos++
+
+	return v, nil
+}
+
+// args parses the arguments of a function call after its "(". Keyword
+// arguments are passed by position.
+func (p *exprParser) args() ([]value, error) {
+	var args []value
+
+	for {
+		t, ok := p.peek()
+		if !ok {
+			return nil, fmt.Errorf("expected \")\"")
+		}
+		if t.kind == tokRParen {
+			p.pos++
+			return args, nil
+		}
+		if t.kind == tokComma {
+			p.pos++
+			continue
+		}
+		if t.kind == tokVar && p.pos+1 < len(p.toks) && p.toks[p.pos+1].kind == tokColon {
+			p.pos += 2
+		}
+
+		v, err := p.spaceList()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+}
+
+// arithmetic applies op to a and b. Operands that cannot be combined, such as
+// lengths of different units inside calc(), are joined as CSS text instead.
+func arithmetic(op string, a, b value, spaced bool) value {
+	x, xok := a.(number)
+	y, yok := b.(number)
+
+	if xok && yok {
+		switch op {
+		case "*":
+			if x.unit == "" || y.unit == "" {
+				return number{v: x.v * y.v, unit: x.unit + y.unit}
+			}
+		case "%":
+			if y.v != 0 && (x.unit == y.unit || y.unit == "") {
+				return number{v: math.Mod(x.v, y.v), unit: x.unit}
+			}
+		case "+", "-":
+			if x.unit == y.unit || x.unit == "" || y.unit == "" {
+				unit := x.unit
+				if unit == "" {
+					unit = y.unit
+				}
+				if op == "-" {
+					return number{v: x.v - y.v, unit: unit}
+				}
+				return number{v: x.v + y.v, unit: unit}
+			}
+		}
+	}
+
+	if op == "/" && !spaced {
+		return str{s: a.css() + "/" + b.css()}
+	}
+
+	return str{s: a.css() + " " + op + " " + b.css()}
+}
+
+// call evaluates the SCSS function name. A module prefix such as "math." is
+// ignored, and functions that are not SCSS functions are plain CSS, e.g.
+// rgb(), var() or calc(), and output as written.
+func call(name string, args []value) (value, error) {
+	fn := name
+	if i := strings.LastIndexByte(fn, '.'); i >= 0 {
+		fn = fn[i+1:]
+	}
+
+	argn := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("%s() takes %d arguments, got %d", name, n, len(args))
+		}
+		return nil
+	}
+
+	switch fn {
+	case "div":
+		if err := argn(2); err != nil {
+			return nil, err
+		}
+		x, xok := args[0].(number)
+		y, yok := args[1].(number)
+		if !xok || !yok {
+			return str{s: args[0].css() + "/" + args[1].css()}, nil
+		}
+		if y.v == 0 {
+			return nil, fmt.Errorf("%s(): division by zero", name)
+		}
+		unit := x.unit
+		if x.unit == y.unit {
+			unit = ""
+		} else if x.unit == "" {
+			unit = y.unit
+		}
+		return number{v: x.v / y.v, unit: unit}, nil
+
+	case "percentage":
+		if err := argn(1); err != nil {
+			return nil, err
+		}
+		x, ok := args[0].(number)
+		if !ok || x.unit != "" {
+			return nil, fmt.Errorf("%s(): %s is not a unitless number", name, args[0].css())
+		}
+		return number{v: x.v * 100, unit: "%"}, nil
+
+	case "round", "ceil", "floor", "abs":
+		if err := argn(1); err != nil {
+			return nil, err
+		}
+		x, ok := args[0].(number)
+		if !ok {
+			break
+		}
+		switch fn {
+		case "round":
+			x.v = math.Round(x.v)
+		case "ceil":
+			x.v = math.Ceil(x.v)
+		case "floor":
+			x.v = math.Floor(x.v)
+		case "abs":
+			x.v = math.Abs(x.v)
+		}
+		return x, nil
+
+	case "unquote":
+		if err := argn(1); err != nil {
+			return nil, err
+		}
+		return str{s: unquoted(args[0])}, nil
+
+	case "quote":
+		if err := argn(1); err != nil {
+			return nil, err
+		}
+		return str{s: unquoted(args[0]), quote: '"'}, nil
+
+	case "map-get":
+		if err := argn(2); err != nil {
+			return nil, err
+		}
+		m, ok := args[0].(mapValue)
+		if !ok {
+			return nil, fmt.Errorf("%s(): %s is not a map", name, args[0].css())
+		}
+		for i, key := range m.keys {
+			if unquoted(key) == unquoted(args[1]) {
+				return m.vals[i], nil
+			}
+		}
+		return str{}, nil
+
+	case "nth":
+		if err := argn(2); err != nil {
+			return nil, err
+		}
+		n, ok := args[1].(number)
+		list := items(args[0])
+		if !ok || n.v == 0 || math.Abs(n.v) > float64(len(list)) {
+			return nil, fmt.Errorf("%s(): invalid index %s", name, args[1].css())
+		}
+		i := int(n.v) - 1
+		if n.v < 0 {
+			i = len(list) + int(n.v)
+		}
+		return list[i], nil
+
+	case "length":
+		if err := argn(1); err != nil {
+			return nil, err
+		}
+		return number{v: float64(len(items(args[0])))}, nil
+	}
+
+	if fn == "get" && strings.HasPrefix(name, "map.") {
+		return call("map-get", args)
+	}
+
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = arg.css()
+	}
+
+	return str{s: name + "(" + strings.Join(parts, ", ") + ")"}, nil
+}
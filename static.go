@@ -0,0 +1,293 @@
+package lemur
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// publicDirs are the theme directories served by StaticHandler, in order of
+// lookup.
+var publicDirs = []string{STATIC_DIR_PATH, path.Join(LAYOUTS_DIR_PATH, PUBLIC_DIR)}
+
+// precompressed are the encodings StaticHandler serves from precompressed
+// variants of a file, in order of preference, with their file suffix.
+var precompressed = []struct {
+	encoding string
+	suffix   string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// StaticHandler returns an http.Handler serving the files of the theme's
+// static/ and layouts/_public/ directories, looked up in that order, at the
+// root of the URL path it is mounted on, e.g. static/img/logo.png at
+// /img/logo.png. Mount it below a prefix with http.StripPrefix.
+//
+// Responses carry a Content-Type from the file extension, a content hash
+// ETag, and a Last-Modified time when the filesystem reports one, and
+// conditional and range requests are answered as by http.ServeContent. When
+// the client accepts it, a precompressed sibling file such as style.css.br or
+// style.css.gz is served in place of style.css with a Content-Encoding.
+//
+// Files are read from the theme filesystem on every request, so a Lemur built
+// with NewLayered serves a file from the highest layer that has it. Hidden
+// files and directory listings are never served.
+//
+// A path not found in either directory is served from the theme's asset
+// manifest: the files of assets/, the stylesheets compiled from assets/sass/,
+// e.g. /css/style.css from assets/sass/style.scss, and every asset at its
+// fingerprinted path, e.g. /css/style.3f9a1c2b.css, with a Cache-Control header
+// marking it immutable. See the "asset" template func. The images processed by
+// the imageResize and imageFill funcs are served in the same way.
+//
+// An asset that fails to build is answered with a plain 500 response, the
+// error, which names theme files, goes to the func set with WithErrorHandler.
+func (wh *Lemur) StaticHandler() http.Handler {
+	return &staticHandler{fsys: wh.fsys, assets: wh.assets, onErr: wh.onErr, etags: make(map[string]etagEntry)}
+}
+
+// WithErrorHandler sets the func that receives the errors Lemur cannot return
+// to a caller, such as those of StaticHandler building an asset for a request.
+// Without it these errors are dropped.
+func WithErrorHandler(onErr func(error)) Option {
+	return func(wh *Lemur) {
+		wh.onErr = onErr
+	}
+}
+
+type staticHandler struct {
+	fsys   fs.FS
+	assets *assetPipeline
+	onErr  func(error)
+
+	mu    sync.Mutex
+	etags map[string]etagEntry
+}
+
+// etagEntry caches the ETag of a file for as long as its size and
+// modification time stay the same.
+type etagEntry struct {
+	size    int64
+	modTime time.Time
+	etag    string
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" || hasHiddenSegment(name) {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, ok := h.lookup(name)
+	if !ok {
+		h.serveAsset(w, r, name)
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+
+	served := file
+	for _, pc := range precompressed {
+		variant, ok := h.lookup(name + pc.suffix)
+		if !ok {
+			continue
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		if acceptsEncoding(r, pc.encoding) {
+			w.Header().Set("Content-Encoding", pc.encoding)
+			served = variant
+			break
+		}
+	}
+
+	h.serveFile(w, r, served, contentType)
+}
+
+// lookup returns the path of name within the first public directory that has
+// it as a regular file.
+func (h *staticHandler) lookup(name string) (string, bool) {
+	if h.fsys == nil {
+		return "", false
+	}
+
+	for _, dir := range publicDirs {
+		p := path.Join(dir, name)
+		info, err := fs.Stat(h.fsys, p)
+		if err == nil && info.Mode().IsRegular() {
+			return p, true
+		}
+	}
+
+	return "", false
+}
+
+// serveFile serves the file at p with the given Content-Type, or a sniffed one
+// when it is empty.
+func (h *staticHandler) serveFile(w http.ResponseWriter, r *http.Request, p string, contentType string) {
+	f, err := h.fsys.Open(p)
+	if err != nil {
+		serveFileError(w, r, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		serveFileError(w, r, err)
+		return
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			serveFileError(w, r, err)
+			return
+		}
+		content = bytes.NewReader(b)
+	}
+
+	etag, err := h.etag(p, info, content)
+	if err != nil {
+		serveFileError(w, r, err)
+		return
+	}
+
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("ETag", etag)
+
+	// http.ServeContent sniffs the Content-Type when none is set, and answers
+	// If-None-Match, If-Modified-Since and Range requests.
+	http.ServeContent(w, r, p, info.ModTime(), content)
+}
+
+// etag returns the cached ETag of the file at p, hashing content when the file
+// is new or changed. content is left at its start.
+func (h *staticHandler) etag(p string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	h.mu.Lock()
+	entry, ok := h.etags[p]
+	h.mu.Unlock()
+
+	if ok && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		return entry.etag, nil
+	}
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	etag := strconv.Quote(hex.EncodeToString(sum.Sum(nil)[:16]))
+
+	h.mu.Lock()
+	h.etags[p] = etagEntry{size: info.Size(), modTime: info.ModTime(), etag: etag}
+	h.mu.Unlock()
+
+	return etag, nil
+}
+
+// serveAsset serves the asset of the theme's manifest at name, either a
+// fingerprinted name or a processed image, which are cached by clients for
+// good, or the logical name of a compiled stylesheet or a file of assets/.
+func (h *staticHandler) serveAsset(w http.ResponseWriter, r *http.Request, name string) {
+	if h.assets == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if file, ok := h.assets.processedImage(name); ok {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		http.ServeFile(w, r, file)
+		return
+	}
+
+	e, ok, err := h.assets.lookupFingerprinted(name)
+	if err == nil && ok {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else if err == nil {
+		e, ok, err = h.assets.entry(name)
+	}
+	if err != nil {
+		// The error names theme files, it is reported rather than sent.
+		if h.onErr != nil {
+			h.onErr(fmt.Errorf("lemur StaticHandler: serving %s: %w", name, err))
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.assets.serve(w, r, e)
+}
+
+func serveFileError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func hasHiddenSegment(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header of r accepts the
+// encoding with a non-zero quality.
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(header, ",") {
+			fields := strings.Split(part, ";")
+			if strings.TrimSpace(fields[0]) != encoding {
+				continue
+			}
+
+			accepted := true
+			for _, param := range fields[1:] {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") {
+					q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+					accepted = err == nil && q > 0
+				}
+			}
+			return accepted
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,152 @@
+package lemur_test
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ukiahsmith/lemur"
+)
+
+func TestLemur_StaticHandler(t *testing.T) {
+	modTime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	base := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`x`)},
+		"layouts/_public/favicon.ico":        &fstest.MapFile{Data: []byte("ico"), ModTime: modTime},
+		"layouts/_public/robots.txt":         &fstest.MapFile{Data: []byte("public robots")},
+		"static/robots.txt":                  &fstest.MapFile{Data: []byte("static robots")},
+		"static/css/style.css":               &fstest.MapFile{Data: []byte("body{}"), ModTime: modTime},
+		"static/css/style.css.gz":            &fstest.MapFile{Data: []byte("gzipped")},
+		"static/css/style.css.br":            &fstest.MapFile{Data: []byte("brotli")},
+		"static/img/logo.png":                &fstest.MapFile{Data: []byte("base logo")},
+		"static/.secret":                     &fstest.MapFile{Data: []byte("secret")},
+	}
+	site := fstest.MapFS{
+		"layouts/_defaults/.keep": &fstest.MapFile{},
+		"static/img/logo.png":     &fstest.MapFile{Data: []byte("site logo")},
+	}
+
+	wh, err := lemur.NewLayered([]fs.FS{base, site}, nil)
+	if err != nil {
+		t.Fatalf("lemur.NewLayered failed during setup: %v", err)
+	}
+	handler := wh.StaticHandler()
+
+	testCases := []struct {
+		Name            string
+		Method          string
+		Path            string
+		AcceptEncoding  string
+		Status          int
+		Body            string
+		ContentType     string
+		ContentEncoding string
+	}{
+		{"static file", "GET", "/css/style.css", "", 200, "body{}", "text/css; charset=utf-8", ""},
+		{"brotli preferred", "GET", "/css/style.css", "gzip, br", 200, "brotli", "text/css; charset=utf-8", "br"},
+		{"gzip", "GET", "/css/style.css", "gzip", 200, "gzipped", "text/css; charset=utf-8", "gzip"},
+		{"brotli refused", "GET", "/css/style.css", "br;q=0, gzip", 200, "gzipped", "text/css; charset=utf-8", "gzip"},
+		{"public file", "GET", "/favicon.ico", "", 200, "ico", "image/vnd.microsoft.icon", ""},
+		{"static before public", "GET", "/robots.txt", "", 200, "static robots", "text/plain; charset=utf-8", ""},
+		{"layer override", "GET", "/img/logo.png", "", 200, "site logo", "image/png", ""},
+		{"hidden file", "GET", "/.secret", "", 404, "", "", ""},
+		{"directory", "GET", "/css", "", 404, "", "", ""},
+		{"missing", "GET", "/missing.js", "", 404, "", "", ""},
+		{"post", "POST", "/css/style.css", "", 405, "", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.Method, tc.Path, nil)
+			if tc.AcceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.AcceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.Status {
+				t.Fatalf("Expected status %d, but got %d", tc.Status, rec.Code)
+			}
+			if tc.Status != http.StatusOK {
+				return
+			}
+
+			if rec.Body.String() != tc.Body {
+				t.Errorf("Expected body %q, but got %q", tc.Body, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != tc.ContentType {
+				t.Errorf("Expected Content-Type %q, but got %q", tc.ContentType, ct)
+			}
+			if ce := rec.Header().Get("Content-Encoding"); ce != tc.ContentEncoding {
+				t.Errorf("Expected Content-Encoding %q, but got %q", tc.ContentEncoding, ce)
+			}
+			if rec.Header().Get("ETag") == "" {
+				t.Errorf("Expected an ETag, but got none")
+			}
+		})
+	}
+}
+
+func TestLemur_StaticHandler_Conditional(t *testing.T) {
+	modTime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	wh, err := lemur.New(fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`x`)},
+		"static/app.js":                      &fstest.MapFile{Data: []byte("app()"), ModTime: modTime},
+	}, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+	handler := wh.StaticHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/app.js", nil))
+
+	etag := rec.Header().Get("ETag")
+	if lm := rec.Header().Get("Last-Modified"); lm != modTime.Format(http.TimeFormat) {
+		t.Errorf("Expected Last-Modified %q, but got %q", modTime.Format(http.TimeFormat), lm)
+	}
+
+	testCases := []struct {
+		Name   string
+		Header string
+		Value  string
+		Status int
+	}{
+		{"matching etag", "If-None-Match", etag, http.StatusNotModified},
+		{"other etag", "If-None-Match", `"other"`, http.StatusOK},
+		{"not modified since", "If-Modified-Since", modTime.Format(http.TimeFormat), http.StatusNotModified},
+		{"range", "Range", "bytes=0-2", http.StatusPartialContent},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/app.js", nil)
+			req.Header.Set(tc.Header, tc.Value)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.Status {
+				t.Errorf("Expected status %d, but got %d", tc.Status, rec.Code)
+			}
+		})
+	}
+}
+
+func TestLemur_PublicIsNotALayout(t *testing.T) {
+	wh, err := lemur.New(fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`x`)},
+		"layouts/_public/broken.html":        &fstest.MapFile{Data: []byte(`{{ not a template`)},
+	}, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed: %v", err)
+	}
+
+	for _, name := range wh.Layouts() {
+		if name == lemur.PUBLIC_DIR {
+			t.Errorf("Expected %s not to be a layout, but got %v", lemur.PUBLIC_DIR, wh.Layouts())
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package lemur
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+)
+
+// isTextTemplate reports whether the template file or entry name is a plain
+// text template, loaded with text/template rather than html/template.
+func isTextTemplate(name string) bool {
+	return strings.HasSuffix(name, TEXT_TEMPLATE_EXT)
+}
+
+// SrenderText renders the "_index.txt.tmpl" entry of the named layout set and
+// returns the output as a string, see RenderText.
+func (wh *Lemur) SrenderText(layout string, data interface{}) (string, error) {
+	return wh.SrenderTemplate(layout, TEXT_TEMPLATE_INDEX, data)
+}
+
+// RenderText executes the "_index.txt.tmpl" entry of the named layout set,
+// writing the output to w without any HTML escaping. It is used for plain text
+// emails, robots.txt and similar files.
+//
+// Files named *.txt.tmpl are loaded with text/template into a text set per
+// layout, separate from the HTML set but inheriting from the *.txt.tmpl files
+// in _defaults in the same way. Any *.txt.tmpl entry may also be rendered with
+// RenderTemplate, e.g. "robots.txt.tmpl".
+func (wh *Lemur) RenderText(w io.Writer, layout string, data interface{}) error {
+	return wh.RenderTemplate(w, layout, TEXT_TEMPLATE_INDEX, data)
+}
+
+// loadTextTemplates parses the *.txt.tmpl files of _defaults into a text base
+// template, and clones it for every layout set with that layout's *.txt.tmpl
+// files parsed on top. templateFS must already be validated.
+func loadTextTemplates(templateFS fs.FS, funcMap template.FuncMap) (*texttemplate.Template, map[string]*texttemplate.Template, error) {
+	var errs LoadErrors
+
+	base := texttemplate.New("lemur").Funcs(texttemplate.FuncMap(funcMap))
+
+	defaultsDirFullPath := filepath.Join(LAYOUTS_DIR_PATH, DEFAULT_TEMPLATE)
+	base, err := parseTextTemplateFiles(templateFS, base, LAYOUTS_DIR_PATH, DEFAULT_TEMPLATE)
+	errs.add(DEFAULT_TEMPLATE, defaultsDirFullPath, err)
+
+	entries, err := fs.ReadDir(templateFS, LAYOUTS_DIR_PATH)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: reading %s from filesystem: %s", ErrTemplateDir, LAYOUTS_DIR_PATH, err)
+	}
+
+	layouts := make(map[string]*texttemplate.Template)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || !isLayoutDir(name) {
+			continue
+		}
+
+		tmpl, err := processTextLayoutDirectory(templateFS, base, LAYOUTS_DIR_PATH, name)
+		if err != nil {
+			errs.add(name, filepath.Join(LAYOUTS_DIR_PATH, name), err)
+			continue
+		}
+		layouts[name] = tmpl
+	}
+
+	if err := errs.err(); err != nil {
+		return nil, nil, err
+	}
+
+	return base, layouts, nil
+}
+
+// processTextLayoutDirectory clones the text base template and parses the
+// *.txt.tmpl files of a single layout directory into it. A file with the same
+// name as one in _defaults replaces it.
+func processTextLayoutDirectory(templateFS fs.FS, base *texttemplate.Template, layoutsDirPath string, layoutName string) (*texttemplate.Template, error) {
+	ctmpl, err := base.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone text base template for set %s: %w", layoutName, err)
+	}
+
+	return parseTextTemplateFiles(templateFS, ctmpl, layoutsDirPath, layoutName)
+}
+
+// parseTextTemplateFiles parses every *.txt.tmpl file below the layout
+// directory into tmpl. Every file is attempted, a returned error is a
+// LoadErrors listing each file that failed.
+func parseTextTemplateFiles(templateFS fs.FS, tmpl *texttemplate.Template, layoutsDirPath string, layoutName string) (*texttemplate.Template, error) {
+	var errs LoadErrors
+	layoutPathRel := filepath.Join(layoutsDirPath, layoutName)
+
+	files, err := templateFiles(templateFS, layoutPathRel)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return tmpl, nil
+		}
+		return nil, fmt.Errorf("error reading directory for template set %s from filesystem: %w", layoutName, err)
+	}
+
+	for _, fileName := range files {
+		if !isTextTemplate(fileName) {
+			continue
+		}
+
+		filePathRel := filepath.Join(layoutPathRel, fileName)
+		content, err := fs.ReadFile(templateFS, filePathRel)
+		if err != nil {
+			errs.add(layoutName, filePathRel, fmt.Errorf("failed to read template file %q: %w", filePathRel, err))
+			continue
+		}
+
+		if _, err := tmpl.New(fileName).Parse(string(content)); err != nil {
+			errs.add(layoutName, filePathRel, fmt.Errorf("failed to parse template file %q: %w", filePathRel, err))
+			continue
+		}
+	}
+
+	return tmpl, errs.err()
+}
+
+// bindTextContext is bindContext for a text set. Unlike html/template, a text
+// template may be cloned after it has been executed.
+func (wh *Lemur) bindTextContext(ctx context.Context, tmpl *texttemplate.Template) (*texttemplate.Template, error) {
+	if len(wh.ctxFuncs) == 0 {
+		return tmpl, nil
+	}
+
+	bound, err := bindContextFuncs(ctx, wh.ctxFuncs)
+	if err != nil {
+		return nil, err
+	}
+
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone template set: %w", err)
+	}
+
+	return clone.Funcs(texttemplate.FuncMap(bound)), nil
+}
@@ -0,0 +1,115 @@
+package lemur_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ukiahsmith/lemur"
+)
+
+func textTestFS() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`<p>{{ . }}</p>`)},
+		"layouts/_defaults/_index.txt.tmpl":  &fstest.MapFile{Data: []byte(`Hello <{{ . }}> {{ block "body.txt.tmpl" . }}default body{{ end }} {{ mod 7 3 }}`)},
+		"layouts/_defaults/robots.txt.tmpl":  &fstest.MapFile{Data: []byte("User-agent: *\nDisallow: {{ . }}\n")},
+		"layouts/order/body.txt.tmpl":        &fstest.MapFile{Data: []byte(`order body & more`)},
+		"layouts/order/main.html.tmpl":       &fstest.MapFile{Data: []byte(`unused`)},
+		"layouts/profile/main.html.tmpl":     &fstest.MapFile{Data: []byte(`unused`)},
+	}
+}
+
+func TestLemur_RenderText(t *testing.T) {
+	wh, err := lemur.New(textTestFS(), nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	testCases := []struct {
+		Name     string
+		Layout   string
+		Entry    string
+		Expected string
+	}{
+		{"defaults", "", lemur.TEXT_TEMPLATE_INDEX, "Hello <a&b> default body 1"},
+		{"layout override", "order", lemur.TEXT_TEMPLATE_INDEX, "Hello <a&b> order body & more 1"},
+		{"layout inherits", "profile", lemur.TEXT_TEMPLATE_INDEX, "Hello <a&b> default body 1"},
+		{"named text entry", "profile", "robots.txt.tmpl", "User-agent: *\nDisallow: a&b\n"},
+		{"html is still escaped", "", lemur.DEFAULT_TEMPLATE_INDEX, "<p>a&amp;b</p>"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			out, err := wh.SrenderTemplate(tc.Layout, tc.Entry, "a&b")
+			if err != nil {
+				t.Fatalf("SrenderTemplate failed: %v", err)
+			}
+
+			if out != tc.Expected {
+				t.Errorf("Expected output %q, but got %q", tc.Expected, out)
+			}
+		})
+	}
+
+	var buf strings.Builder
+	if err := wh.RenderText(&buf, "order", "x"); err != nil {
+		t.Fatalf("RenderText failed: %v", err)
+	}
+	if expected := "Hello <x> order body & more 1"; buf.String() != expected {
+		t.Errorf("Expected output %q, but got %q", expected, buf.String())
+	}
+}
+
+func TestLemur_RenderText_Errors(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`html`)},
+	}
+
+	wh, err := lemur.New(templateFS, nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	_, err = wh.SrenderText("", nil)
+	if !errors.Is(err, lemur.ErrUnknownTemplate) {
+		t.Errorf("Expected error to match %v, but got %v", lemur.ErrUnknownTemplate, err)
+	}
+}
+
+func TestLemur_RenderText_LoadError(t *testing.T) {
+	templateFS := fstest.MapFS{
+		"layouts/_defaults/_index.html.tmpl": &fstest.MapFile{Data: []byte(`html`)},
+		"layouts/order/email.txt.tmpl":       &fstest.MapFile{Data: []byte("line one\n{{ .Missing ")},
+	}
+
+	_, err := lemur.New(templateFS, nil)
+
+	var loadErrs lemur.LoadErrors
+	if !errors.As(err, &loadErrs) {
+		t.Fatalf("Expected a LoadErrors, but got %v", err)
+	}
+	if len(loadErrs) != 1 {
+		t.Fatalf("Expected 1 load error, but got %d: %v", len(loadErrs), err)
+	}
+	if loadErrs[0].Path != "layouts/order/email.txt.tmpl" || loadErrs[0].Line != 2 {
+		t.Errorf("Expected error at layouts/order/email.txt.tmpl:2, but got %s:%d", loadErrs[0].Path, loadErrs[0].Line)
+	}
+}
+
+func TestLemur_Templates_IncludesText(t *testing.T) {
+	wh, err := lemur.New(textTestFS(), nil)
+	if err != nil {
+		t.Fatalf("lemur.New failed during setup: %v", err)
+	}
+
+	names, err := wh.Templates("order")
+	if err != nil {
+		t.Fatalf("Templates failed: %v", err)
+	}
+
+	expected := "_index.html.tmpl,_index.txt.tmpl,body.txt.tmpl,main.html.tmpl,robots.txt.tmpl"
+	if got := strings.Join(names, ","); got != expected {
+		t.Errorf("Expected templates %q, but got %q", expected, got)
+	}
+}